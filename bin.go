@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Executable an executable of the module, linked into %{_bindir}
+type Executable struct {
+	Name string
+	// Path relative to the module
+	Path string
+}
+
+// ManPage a man page of the module, installed into %{_mandir}
+type ManPage struct {
+	// Path relative to the module
+	Path    string
+	Name    string
+	Section string
+}
+
+// manPageRe a man page file name and its section, eg: "foo.1", "foo.3pm.gz"
+var manPageRe = regexp.MustCompile(`^(.+)\.(\d\w*)(\.gz)?$`)
+
+// modulePath a path of package.json relative to the module, "./bin/x" is "bin/x".
+// Paths leaving the module are empty
+func modulePath(s string) string {
+	p := strings.TrimPrefix(path.Clean(strings.TrimPrefix(s, "./")), "/")
+	if p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return ""
+	}
+	return p
+}
+
+// specSafe if a name or path of package.json can go into %install and %files
+// as it is, without shell quoting or rpm macros
+func specSafe(s string) bool {
+	return shellSafeRe.MatchString(s) && !strings.Contains(s, "%")
+}
+
+// Executables the executables of the node, from "bin" or every file in
+// "directories.bin" of the files of its tarball, sorted by name. Like npm
+// only the base name of a "bin" key is linked.
+func Executables(n *Node, files []string) []Executable {
+	m := map[string]string{}
+	for k, v := range n.Bin {
+		name, p := path.Base(k), modulePath(v)
+		if name == "." || name == ".." || len(p) == 0 || !specSafe(name) || !specSafe(p) {
+			log.Printf("WARNING: %s: bin %q -> %q is skipped, it leaves the module or needs quoting.", n.Name, k, v)
+			continue
+		}
+		m[name] = p
+	}
+	// "bin" wins over "directories.bin", like npm does
+	if len(n.Bin) == 0 && len(n.BinDir) > 0 {
+		dir := modulePath(n.BinDir) + "/"
+		for _, f := range files {
+			if dir == "/" || !strings.HasPrefix(f, dir) || strings.Contains(strings.TrimPrefix(f, dir), "/") {
+				continue
+			}
+			if !specSafe(f) {
+				log.Printf("WARNING: %s: %q in directories.bin is skipped, it needs quoting.", n.Name, f)
+				continue
+			}
+			m[path.Base(f)] = f
+		}
+	}
+	a := []Executable{}
+	for k, v := range m {
+		a = append(a, Executable{k, v})
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i].Name < a[j].Name })
+	return a
+}
+
+// ManPages the man pages of the node, from "man" or every man page in
+// "directories.man" of the files of its tarball, sorted by path
+func ManPages(n *Node, files []string) []ManPage {
+	paths := []string{}
+	for _, v := range n.Man {
+		paths = append(paths, modulePath(v))
+	}
+	if len(n.Man) == 0 && len(n.ManDir) > 0 {
+		dir := modulePath(n.ManDir) + "/"
+		for _, f := range files {
+			if dir != "/" && strings.HasPrefix(f, dir) {
+				paths = append(paths, f)
+			}
+		}
+	}
+	sort.Strings(paths)
+	a := []ManPage{}
+	for _, v := range paths {
+		if len(v) == 0 || !specSafe(v) {
+			log.Printf("WARNING: %s: man page %q is skipped, it leaves the module or needs quoting.", n.Name, v)
+			continue
+		}
+		m := manPageRe.FindStringSubmatch(path.Base(v))
+		if m == nil {
+			continue
+		}
+		a = append(a, ManPage{v, m[1] + "." + m[2], m[2][:1]})
+	}
+	return a
+}
+
+// binLines the %install lines linking the executables and installing the man
+// pages of the module
+func binLines(bins []Executable, mans []ManPage) string {
+	str := ""
+	if len(bins) > 0 {
+		str += "mkdir -p %{buildroot}%{_bindir}\n"
+	}
+	for _, v := range bins {
+		str += "chmod 0755 %{buildroot}%{nodejs_sitelib}/%{mod_name}/" + v.Path + "\n"
+		str += "ln -s %{nodejs_sitelib}/%{mod_name}/" + v.Path + " %{buildroot}%{_bindir}/" + v.Name + "\n"
+	}
+	for _, v := range mans {
+		// brp-compress compresses the others
+		name := v.Name
+		if strings.HasSuffix(v.Path, ".gz") {
+			name += ".gz"
+		}
+		str += "install -D -m 0644 %{buildroot}%{nodejs_sitelib}/%{mod_name}/" + v.Path + " %{buildroot}%{_mandir}/man" + v.Section + "/" + name + "\n"
+	}
+	return str
+}
+
+// binFiles the %files lines of the executables and man pages
+func binFiles(bins []Executable, mans []ManPage) string {
+	str := ""
+	for _, v := range bins {
+		str += "%{_bindir}/" + v.Name + "\n"
+	}
+	for _, v := range mans {
+		str += "%{_mandir}/man" + v.Section + "/" + v.Name + "%{?ext_man}\n"
+	}
+	return str
+}
+
+// BinConflicts the executables whose names are taken in the distribution
+func BinConflicts(bins []Executable, distro map[string]string) map[string]string {
+	m := map[string]string{}
+	for _, v := range bins {
+		if owner, ok := distro[v.Name]; ok {
+			m[v.Name] = owner
+		}
+	}
+	return m
+}
+
+// ReadDistroBins the executables in /usr/bin of the distribution and the rpm
+// owning them, either from a repodata primary.xml or a list of paths or names
+// like "ls /usr/bin" or "rpm -qal" output
+func ReadDistroBins(b []byte) map[string]string {
+	m := map[string]string{}
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("<")) {
+		d := xml.NewDecoder(bytes.NewReader(b))
+		name, text := "", ""
+		for {
+			t, e := d.Token()
+			if e != nil {
+				// io.EOF or a broken file, take what we have
+				break
+			}
+			switch v := t.(type) {
+			case xml.StartElement:
+				text = ""
+			case xml.CharData:
+				text += string(v)
+			case xml.EndElement:
+				switch v.Name.Local {
+				case "name":
+					name = strings.TrimSpace(text)
+				case "file":
+					if f := strings.TrimSpace(text); strings.HasPrefix(f, "/usr/bin/") {
+						m[path.Base(f)] = name
+					}
+				}
+			}
+		}
+		return m
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		f := strings.TrimSpace(scanner.Text())
+		// skip other paths and anything else, eg: "rpm -qa --provides" output
+		if len(f) == 0 || strings.ContainsAny(f, " \t()") || (strings.Contains(f, "/") && !strings.HasPrefix(f, "/usr/bin/")) {
+			continue
+		}
+		m[path.Base(f)] = ""
+	}
+	return m
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_Executables(t *testing.T) {
+	n := &Node{Name: "semver", Bin: map[string]string{"semver": "./bin/semver.js", "semver-x": "bin/x.js"}}
+	expected := []Executable{{"semver", "bin/semver.js"}, {"semver-x", "bin/x.js"}}
+	if a := Executables(n, nil); !reflect.DeepEqual(a, expected) {
+		t.Errorf("Test Executables() failed, expected %v, got %v", expected, a)
+	}
+	dir := &Node{Name: "foo", BinDir: "./bin"}
+	files := []string{"package.json", "bin/foo", "bin/lib/helper.js", "lib/index.js"}
+	expected = []Executable{{"foo", "bin/foo"}}
+	if a := Executables(dir, files); !reflect.DeepEqual(a, expected) {
+		t.Errorf("Test Executables() failed, expected %v, got %v", expected, a)
+	}
+	// names and paths of package.json stay in %{_bindir} and the module
+	evil := &Node{Name: "evil", Bin: map[string]string{
+		"../../etc/x": "bin/x.js",
+		"y":           "../../../usr/bin/y",
+		"z":           "..",
+		"a;b":         "bin/a.js",
+		"c":           "bin/$(c).js",
+	}}
+	expected = []Executable{{"x", "bin/x.js"}}
+	if a := Executables(evil, nil); !reflect.DeepEqual(a, expected) {
+		t.Errorf("Test Executables() failed, expected %v, got %v", expected, a)
+	}
+}
+
+func Test_ManPages(t *testing.T) {
+	n := &Node{Name: "npm", Man: []string{"./man/npm.1", "man/npmrc.5.gz", "README.md"}}
+	expected := []ManPage{{"man/npm.1", "npm.1", "1"}, {"man/npmrc.5.gz", "npmrc.5", "5"}}
+	if a := ManPages(n, nil); !reflect.DeepEqual(a, expected) {
+		t.Errorf("Test ManPages() failed, expected %v, got %v", expected, a)
+	}
+	dir := &Node{Name: "foo", ManDir: "doc"}
+	expected = []ManPage{{"doc/foo.3pm", "foo.3pm", "3"}}
+	if a := ManPages(dir, []string{"doc/foo.3pm", "doc/index.html", "foo.1"}); !reflect.DeepEqual(a, expected) {
+		t.Errorf("Test ManPages() failed, expected %v, got %v", expected, a)
+	}
+	evil := &Node{Name: "evil", Man: []string{"../../man/evil.1", "man/%{_bindir}.1"}}
+	if a := ManPages(evil, nil); len(a) > 0 {
+		t.Errorf("Test ManPages() failed, expected nothing, got %v", a)
+	}
+}
+
+func Test_binLines(t *testing.T) {
+	bins := []Executable{{"semver", "bin/semver.js"}}
+	mans := []ManPage{{"man/semver.1.gz", "semver.1", "1"}}
+	s := binLines(bins, mans)
+	for _, v := range []string{
+		"mkdir -p %{buildroot}%{_bindir}\n",
+		"ln -s %{nodejs_sitelib}/%{mod_name}/bin/semver.js %{buildroot}%{_bindir}/semver\n",
+		"%{buildroot}%{_mandir}/man1/semver.1.gz\n",
+	} {
+		if !strings.Contains(s, v) {
+			t.Errorf("Test binLines() failed, expected %q in %q", v, s)
+		}
+	}
+	expected := "%{_bindir}/semver\n%{_mandir}/man1/semver.1%{?ext_man}\n"
+	if f := binFiles(bins, mans); f != expected {
+		t.Errorf("Test binFiles() failed, expected %q, got %q", expected, f)
+	}
+	if binLines(nil, nil) != "" || binFiles(nil, nil) != "" {
+		t.Errorf("Test binLines() failed, expected nothing without executables")
+	}
+}
+
+func Test_ReadDistroBins(t *testing.T) {
+	xml := `<metadata><package type="rpm"><name>nodejs-semver</name><format>
+<file>/usr/bin/semver</file><file>/usr/lib/node_modules/semver/bin/semver.js</file>
+</format></package></metadata>`
+	expected := map[string]string{"semver": "nodejs-semver"}
+	if m := ReadDistroBins([]byte(xml)); !reflect.DeepEqual(m, expected) {
+		t.Errorf("Test ReadDistroBins() failed, expected %v, got %v", expected, m)
+	}
+	text := "/usr/bin/semver\nls\n/usr/lib/foo\nnpm(semver) = 7.3.5\n"
+	expected = map[string]string{"semver": "", "ls": ""}
+	if m := ReadDistroBins([]byte(text)); !reflect.DeepEqual(m, expected) {
+		t.Errorf("Test ReadDistroBins() failed, expected %v, got %v", expected, m)
+	}
+	c := BinConflicts([]Executable{{"semver", "bin/semver.js"}, {"foo", "bin/foo"}}, expected)
+	if !reflect.DeepEqual(c, map[string]string{"semver": ""}) {
+		t.Errorf("Test BinConflicts() failed, got %v", c)
+	}
+}
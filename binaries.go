@@ -0,0 +1,221 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// kinds of files openSUSE doesn't want in a package built from source
+const (
+	BinaryELF      = "ELF binary"
+	BinaryMachO    = "Mach-O binary"
+	BinaryPE       = "PE binary"
+	BinaryAddon    = "prebuilt addon"
+	BinaryPrebuild = "prebuilds directory"
+	BinaryWasm     = "wasm blob"
+)
+
+// binaryMagics the leading bytes of executable formats
+var binaryMagics = []struct {
+	Magic []byte
+	Kind  string
+}{
+	{[]byte("\x7fELF"), BinaryELF},
+	{[]byte{0xfe, 0xed, 0xfa, 0xce}, BinaryMachO},
+	{[]byte{0xfe, 0xed, 0xfa, 0xcf}, BinaryMachO},
+	{[]byte{0xce, 0xfa, 0xed, 0xfe}, BinaryMachO},
+	{[]byte{0xcf, 0xfa, 0xed, 0xfe}, BinaryMachO},
+	{[]byte("\x00asm"), BinaryWasm},
+}
+
+// BinaryInspection what a module's tarball ships besides plain sources
+type BinaryInspection struct {
+	Node *Node
+	// Path the install path of the module in the tree
+	Path string
+	// Files the binary files, relative to the module, and their kind
+	Files map[string]string
+	// Minified if every javascript file of the module is minified
+	Minified bool
+}
+
+// InspectBinaries look into the tarball of every module of the tree for
+// prebuilt binaries, wasm blobs and minified-only sources. Only modules with
+// findings are returned, sorted by their install path.
+func InspectBinaries(doc TreeDocument, nodes Nodes, cache ResponseCache) []BinaryInspection {
+	a := []BinaryInspection{}
+	doc.Root.Walk(func(tn TreeNode) {
+		n, ok := nodes[tn.Name+":"+tn.Version]
+		if !ok || len(n.Tarball) == 0 {
+			return
+		}
+		// a broken tarball isn't our business here, VerifyLicenses reports it
+		i, _ := inspectBinaries(n, tn.Path, getHttpBody(n.Tarball, cache))
+		if len(i.Files) > 0 || i.Minified {
+			a = append(a, i)
+		}
+	})
+	sort.Slice(a, func(i, j int) bool { return a[i].Path < a[j].Path })
+	return a
+}
+
+// inspectBinaries inspect the files of a gzipped npm tarball
+func inspectBinaries(n *Node, dir string, body []byte) (BinaryInspection, error) {
+	i := BinaryInspection{Node: n, Path: dir, Files: map[string]string{}}
+	gz, e := gzip.NewReader(bytes.NewReader(body))
+	if e != nil {
+		return i, e
+	}
+	defer gz.Close()
+	js, minified := 0, 0
+	tr := tar.NewReader(gz)
+	for {
+		hdr, e := tr.Next()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return i, e
+		}
+		a := strings.SplitN(path.Clean(hdr.Name), "/", 2)
+		if len(a) < 2 || !hdr.FileInfo().Mode().IsRegular() {
+			continue
+		}
+		name := a[1]
+		b, e := ioutil.ReadAll(tr)
+		if e != nil {
+			return i, e
+		}
+		if kind := binaryKind(name, b); len(kind) > 0 {
+			i.Files[name] = kind
+			continue
+		}
+		switch path.Ext(name) {
+		case ".js", ".mjs", ".cjs":
+			js++
+			if isMinified(name, b) {
+				minified++
+			}
+		}
+	}
+	i.Minified = js > 0 && js == minified
+	return i, nil
+}
+
+// binaryKind the kind of a binary file by its name and magic, empty for sources
+func binaryKind(name string, b []byte) string {
+	if path.Ext(name) == ".node" {
+		return BinaryAddon
+	}
+	for _, v := range binaryMagics {
+		if bytes.HasPrefix(b, v.Magic) {
+			return v.Kind
+		}
+	}
+	// a DOS header and the offset of the PE signature it points to
+	if bytes.HasPrefix(b, []byte("MZ")) && len(b) > 0x40 {
+		off := int(b[0x3c]) | int(b[0x3d])<<8 | int(b[0x3e])<<16 | int(b[0x3f])<<24
+		if off > 0 && off+4 <= len(b) && bytes.Equal(b[off:off+4], []byte("PE\x00\x00")) {
+			return BinaryPE
+		}
+	}
+	if strings.HasPrefix(name, "prebuilds/") || strings.Contains(name, "/prebuilds/") {
+		return BinaryPrebuild
+	}
+	if path.Ext(name) == ".wasm" {
+		return BinaryWasm
+	}
+	return ""
+}
+
+// isMinified if a javascript file is minified, by its name or its long lines
+func isMinified(name string, b []byte) bool {
+	if strings.HasSuffix(name, ".min.js") {
+		return true
+	}
+	if len(b) < 1024 {
+		return false
+	}
+	lines := bytes.Count(b, []byte("\n")) + 1
+	return len(b)/lines > 300
+}
+
+// removals the paths to remove in %prep, a prebuilds directory as a whole.
+// Paths outside the module or spanning lines are reported and left out.
+func (i BinaryInspection) removals() []string {
+	m := map[string]struct{}{}
+	for f, kind := range i.Files {
+		if kind == BinaryWasm {
+			// wasm may be the only way a module works, it's for the packager to decide
+			continue
+		}
+		if idx := strings.Index("/"+f, "/prebuilds/"); idx >= 0 {
+			f = f[:idx] + "prebuilds"
+		}
+		p := path.Join(i.Path, f)
+		if !strings.HasPrefix(p, i.Path+"/") || strings.ContainsAny(p, "\r\n") {
+			log.Printf("WARNING: %s %s: %q can not be removed safely in %%prep, remove it by hand.", i.Node.Name, i.Node.Version, f)
+			continue
+		}
+		m[p] = struct{}{}
+	}
+	a := []string{}
+	for k := range m {
+		a = append(a, k)
+	}
+	sort.Strings(a)
+	return a
+}
+
+// PrepRemovals suggested %prep lines removing the prebuilt binaries
+func PrepRemovals(inspections []BinaryInspection) []string {
+	a := []string{}
+	for _, i := range inspections {
+		for _, v := range i.removals() {
+			a = append(a, "rm -rf "+shellQuote(v))
+		}
+	}
+	return a
+}
+
+// shellSafeRe paths the shell takes literally without quotes
+var shellSafeRe = regexp.MustCompile(`^[\w@%+=:,./-]+$`)
+
+// shellQuote a path of an untrusted tarball as a single word of a %prep
+// line: single quoted unless it's plain, "%" doubled against rpm macros
+func shellQuote(s string) string {
+	if !shellSafeRe.MatchString(s) {
+		s = "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+	}
+	return strings.Replace(s, "%", "%%", -1)
+}
+
+// BinaryReport debug output of the inspections
+func BinaryReport(inspections []BinaryInspection) string {
+	s := "=== Prebuilt binaries and minified sources ===\n"
+	for _, i := range inspections {
+		s += fmt.Sprintf("|\t%s    |    %s\n", i.Node.Name, i.Node.Version)
+		files := []string{}
+		for f := range i.Files {
+			files = append(files, f)
+		}
+		sort.Strings(files)
+		for _, f := range files {
+			s += "|\t\t" + f + ": " + i.Files[f] + "\n"
+		}
+		if i.Minified {
+			s += "|\t\tonly minified javascript, no readable sources\n"
+		}
+	}
+	s += "=== END ==="
+	return s
+}
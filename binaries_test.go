@@ -0,0 +1,83 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_inspectBinaries(t *testing.T) {
+	pe := make([]byte, 0x84)
+	copy(pe, "MZ")
+	pe[0x3c] = 0x80
+	copy(pe[0x80:], "PE\x00\x00")
+	body := npmTarball(map[string]string{
+		"package.json":                        "{}",
+		"index.js":                            "module.exports = require('node-gyp-build')(__dirname)\n",
+		"build/Release/addon.node":            "\x7fELF",
+		"bin/helper":                          "\x7fELF\x02\x01",
+		"bin/helper.exe":                      string(pe),
+		"prebuilds/linux-x64/node.napi.node":  "\x7fELF",
+		"prebuilds/darwin-x64/node.napi.node": "\xcf\xfa\xed\xfe",
+		"lib/crypto.wasm":                     "\x00asm\x01\x00\x00\x00",
+		"README.md":                           "MZ is not a PE",
+	})
+	i, e := inspectBinaries(&Node{Name: "addon", Version: "1.0.0"}, "node_modules/addon", body)
+	if e != nil {
+		t.Fatalf("Test inspectBinaries() failed: %s", e)
+	}
+	expected := map[string]string{
+		"build/Release/addon.node":            BinaryAddon,
+		"bin/helper":                          BinaryELF,
+		"bin/helper.exe":                      BinaryPE,
+		"prebuilds/linux-x64/node.napi.node":  BinaryAddon,
+		"prebuilds/darwin-x64/node.napi.node": BinaryAddon,
+		"lib/crypto.wasm":                     BinaryWasm,
+	}
+	if !reflect.DeepEqual(i.Files, expected) || i.Minified {
+		t.Errorf("Test inspectBinaries() failed, expected %v, got %v", expected, i.Files)
+	}
+	removals := PrepRemovals([]BinaryInspection{i})
+	expectedRemovals := []string{
+		"rm -rf node_modules/addon/bin/helper",
+		"rm -rf node_modules/addon/bin/helper.exe",
+		"rm -rf node_modules/addon/build/Release/addon.node",
+		"rm -rf node_modules/addon/prebuilds",
+	}
+	if !reflect.DeepEqual(removals, expectedRemovals) {
+		t.Errorf("Test PrepRemovals() failed, expected %v, got %v", expectedRemovals, removals)
+	}
+
+	// names from the tarball are no shell, and stay in the module
+	i = BinaryInspection{Node: i.Node, Path: "node_modules/addon", Files: map[string]string{
+		"lib/a b;$(touch x)*'.node": BinaryAddon,
+		"lib/%{_bindir}.node":       BinaryAddon,
+		"../../../etc/x.node":       BinaryAddon,
+		"lib/a\nb.node":             BinaryAddon,
+	}}
+	removals = PrepRemovals([]BinaryInspection{i})
+	expectedRemovals = []string{
+		"rm -rf 'node_modules/addon/lib/%%{_bindir}.node'",
+		"rm -rf 'node_modules/addon/lib/a b;$(touch x)*'\\''.node'",
+	}
+	if !reflect.DeepEqual(removals, expectedRemovals) {
+		t.Errorf("Test PrepRemovals() failed, expected %v, got %v", expectedRemovals, removals)
+	}
+}
+
+func Test_isMinified(t *testing.T) {
+	minified := "var a=1;" + strings.Repeat("function f(a){return a+1};", 100)
+	body := npmTarball(map[string]string{
+		"package.json":  "{}",
+		"dist/index.js": minified,
+		"dist/x.min.js": "var a=1",
+	})
+	i, _ := inspectBinaries(&Node{Name: "min", Version: "1.0.0"}, "node_modules/min", body)
+	if !i.Minified {
+		t.Errorf("Test inspectBinaries() failed, min only ships minified javascript")
+	}
+	readable := strings.Repeat("function f(a) {\n  return a + 1\n}\n", 100)
+	if isMinified("index.js", []byte(readable)) {
+		t.Errorf("Test isMinified() failed, readable source is not minified")
+	}
+}
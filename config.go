@@ -0,0 +1,24 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// readTOML decode a TOML config file into v. Keys v doesn't know are fatal,
+// they are most likely typos silently ignored otherwise.
+func readTOML(file, what string, v interface{}) {
+	md, e := toml.DecodeFile(file, v)
+	if e != nil {
+		log.Fatalf("Can not read %s: %s", what, e)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		keys := []string{}
+		for _, k := range undecoded {
+			keys = append(keys, k.String())
+		}
+		log.Fatalf("Unknown keys in %s %s: %s", what, file, strings.Join(keys, ", "))
+	}
+}
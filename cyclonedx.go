@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CycloneDX a CycloneDX 1.5 Software Bill of Materials
+type CycloneDX struct {
+	BomFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     CycloneDXMetadata     `json:"metadata"`
+	Components   []CycloneDXComponent  `json:"components"`
+	Dependencies []CycloneDXDependency `json:"dependencies"`
+}
+
+// CycloneDXMetadata the metadata of the bom, Component is the root module
+type CycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     []CycloneDXTool    `json:"tools"`
+	Component CycloneDXComponent `json:"component"`
+}
+
+// CycloneDXTool the tool generated the bom
+type CycloneDXTool struct {
+	Name string `json:"name"`
+}
+
+// CycloneDXComponent a bundled module
+type CycloneDXComponent struct {
+	Type               string               `json:"type"`
+	BomRef             string               `json:"bom-ref"`
+	Group              string               `json:"group,omitempty"`
+	Name               string               `json:"name"`
+	Version            string               `json:"version"`
+	Purl               string               `json:"purl"`
+	Licenses           []CycloneDXLicense   `json:"licenses,omitempty"`
+	Hashes             []CycloneDXHash      `json:"hashes,omitempty"`
+	ExternalReferences []CycloneDXReference `json:"externalReferences,omitempty"`
+}
+
+// CycloneDXLicense either an SPDX license id or an SPDX license expression
+type CycloneDXLicense struct {
+	License    *CycloneDXLicenseEntry `json:"license,omitempty"`
+	Expression string                 `json:"expression,omitempty"`
+}
+
+// CycloneDXLicenseEntry a single license, an SPDX license id or the name of
+// a license not known to SPDX
+type CycloneDXLicenseEntry struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// CycloneDXHash a hash of the component's tarball
+type CycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+// CycloneDXReference an external reference, the tarball uri here
+type CycloneDXReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// CycloneDXDependency a node of the dependency graph
+type CycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// cycloneDXHashAlgs maps integrity algorithms to CycloneDX hash algorithms
+var cycloneDXHashAlgs = map[string]string{
+	"sha1":   "SHA-1",
+	"sha256": "SHA-256",
+	"sha384": "SHA-384",
+	"sha512": "SHA-512",
+}
+
+// buildTime the creation time of generated documents, SOURCE_DATE_EPOCH if
+// set so the files, which are RPM Sources, don't change on every run
+func buildTime() time.Time {
+	s := os.Getenv("SOURCE_DATE_EPOCH")
+	if len(s) == 0 {
+		return time.Now().UTC()
+	}
+	i, e := strconv.ParseInt(s, 10, 64)
+	if e != nil {
+		log.Fatalf("Invalid SOURCE_DATE_EPOCH %s: %s", s, e)
+	}
+	return time.Unix(i, 0).UTC()
+}
+
+// NewCycloneDX build a CycloneDX bom from the resolved nodes, root is the
+// "name:version" key of the module to package
+func NewCycloneDX(root string, nodes Nodes) CycloneDX {
+	bom := CycloneDX{
+		BomFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		Version:      1,
+		Components:   []CycloneDXComponent{},
+		Dependencies: []CycloneDXDependency{},
+	}
+	bom.Metadata.Timestamp = buildTime().Format(time.RFC3339)
+	bom.Metadata.Tools = []CycloneDXTool{{"node2rpm"}}
+
+	for _, k := range nodes.Keys() {
+		n := nodes[k]
+		c := n.toCycloneDX()
+		if k == root {
+			c.Type = "application"
+			bom.Metadata.Component = c
+		} else {
+			bom.Components = append(bom.Components, c)
+		}
+		dependsOn := []string{}
+		for _, d := range n.Dependencies {
+			if dn, ok := nodes[d]; ok {
+				dependsOn = append(dependsOn, dn.Purl())
+			}
+		}
+		bom.Dependencies = append(bom.Dependencies, CycloneDXDependency{c.BomRef, dependsOn})
+	}
+	bom.SerialNumber = "urn:uuid:" + nodes.UUID()
+
+	return bom
+}
+
+func (n Node) toCycloneDX() CycloneDXComponent {
+	c := CycloneDXComponent{Type: "library", BomRef: n.Purl(), Name: n.Name, Version: n.Version, Purl: n.Purl()}
+	if strings.HasPrefix(n.Name, "@") {
+		a := strings.SplitN(n.Name, "/", 2)
+		c.Group = a[0]
+		c.Name = a[1]
+	}
+	if len(n.UnknownLicenses) > 0 {
+		c.Licenses = []CycloneDXLicense{{License: &CycloneDXLicenseEntry{Name: n.License}}}
+	} else if expr, e := ParseLicenseExpression(n.License); e == nil {
+		if expr.IsLeaf() && len(expr.Exception) == 0 {
+			c.Licenses = []CycloneDXLicense{{License: &CycloneDXLicenseEntry{ID: expr.License}}}
+		} else {
+			c.Licenses = []CycloneDXLicense{{Expression: expr.String()}}
+		}
+	}
+	checksums := n.Checksums()
+	for _, alg := range []string{"sha1", "sha256", "sha384", "sha512"} {
+		if v, ok := checksums[alg]; ok {
+			c.Hashes = append(c.Hashes, CycloneDXHash{cycloneDXHashAlgs[alg], v})
+		}
+	}
+	if len(n.Tarball) > 0 {
+		c.ExternalReferences = []CycloneDXReference{{"distribution", n.Tarball}}
+	}
+	return c
+}
+
+// Save write the bom to "<name>.cdx.json" in the osc working directory
+func (bom CycloneDX) Save(wd, name string) string {
+	file := name + ".cdx.json"
+	b, e := json.MarshalIndent(bom, "", "\t")
+	if e != nil {
+		log.Fatalf("Can not convert the CycloneDX bom to json: %s", e)
+	}
+	if e = ioutil.WriteFile(filepath.Join(wd, file), b, 0644); e != nil {
+		log.Fatalf("Can not write CycloneDX bom %s: %s", file, e)
+	}
+	return file
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func Test_NewCycloneDX(t *testing.T) {
+	nodes := Nodes{}
+	nodes["har-validator:5.1.3"] = &Node{Name: "har-validator", Version: "5.1.3", License: "MIT", Dependencies: []string{"ajv:6.12.6"}}
+	nodes["ajv:6.12.6"] = &Node{Name: "ajv", Version: "6.12.6", License: "MIT OR Apache-2.0"}
+	bom := NewCycloneDX("har-validator:5.1.3", nodes)
+
+	if bom.Metadata.Component.Purl != "pkg:npm/har-validator@5.1.3" {
+		t.Errorf("Test NewCycloneDX() failed, root component is %s", bom.Metadata.Component.Purl)
+	}
+	if len(bom.Components) != 1 || bom.Components[0].Licenses[0].Expression != "MIT OR Apache-2.0" {
+		t.Errorf("Test NewCycloneDX() failed, unexpected components %v", bom.Components)
+	}
+	for _, d := range bom.Dependencies {
+		if d.Ref == "pkg:npm/har-validator@5.1.3" && (len(d.DependsOn) != 1 || d.DependsOn[0] != "pkg:npm/ajv@6.12.6") {
+			t.Errorf("Test NewCycloneDX() failed, unexpected dependencies %v", d.DependsOn)
+		}
+	}
+	if bom.SerialNumber != NewCycloneDX("har-validator:5.1.3", nodes).SerialNumber {
+		t.Errorf("Test NewCycloneDX() failed, serial number is not reproducible")
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "499162500")
+	if ts := NewCycloneDX("har-validator:5.1.3", nodes).Metadata.Timestamp; ts != "1985-10-26T08:15:00Z" {
+		t.Errorf("Test NewCycloneDX() failed, expected the SOURCE_DATE_EPOCH timestamp, got %s", ts)
+	}
+}
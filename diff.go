@@ -0,0 +1,220 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	semver "github.com/openSUSE-zh/node-semver"
+)
+
+// TreeDiff changes of the bundled modules between two dependency trees
+type TreeDiff struct {
+	Old, New TreeNode
+	Modules  []ModuleChange
+	// Scripts modules of the new tree which come with new lifecycle scripts
+	Scripts []TreeNode
+}
+
+// ModuleChange the versions of a module in the old and the new tree.
+// A module may be bundled in several versions.
+type ModuleChange struct {
+	Name string
+	Old  []TreeNode
+	New  []TreeNode
+}
+
+// Kind the kind of the change: added, removed, upgraded, downgraded, changed or relicensed
+func (c ModuleChange) Kind() string {
+	switch {
+	case len(c.Old) == 0:
+		return "added"
+	case len(c.New) == 0:
+		return "removed"
+	case !equalStrings(versionsOf(c.Old), versionsOf(c.New)):
+		if len(c.Old) == 1 && len(c.New) == 1 {
+			if semver.NewSemver(c.New[0].Version).GreaterThan(semver.NewSemver(c.Old[0].Version)) {
+				return "upgraded"
+			}
+			return "downgraded"
+		}
+		return "changed"
+	case !equalStrings(licensesOf(c.Old), licensesOf(c.New)):
+		return "relicensed"
+	}
+	return ""
+}
+
+// NewTreeDiff compare two dependency trees
+func NewTreeDiff(o, n TreeNode) TreeDiff {
+	d := TreeDiff{Old: o, New: n}
+	om := groupByName(o)
+	nm := groupByName(n)
+
+	names := []string{}
+	for k := range om {
+		names = append(names, k)
+	}
+	for k := range nm {
+		if _, ok := om[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		c := ModuleChange{k, om[k], nm[k]}
+		if len(c.Kind()) > 0 {
+			d.Modules = append(d.Modules, c)
+		}
+		for _, v := range c.New {
+			if len(v.InstallScripts) == 0 {
+				continue
+			}
+			known := false
+			for _, v1 := range c.Old {
+				if equalStrings(v1.InstallScripts, v.InstallScripts) {
+					known = true
+				}
+			}
+			if !known {
+				d.Scripts = append(d.Scripts, v)
+			}
+		}
+	}
+	if len(n.InstallScripts) > 0 && !equalStrings(o.InstallScripts, n.InstallScripts) {
+		d.Scripts = append([]TreeNode{n}, d.Scripts...)
+	}
+	return d
+}
+
+// Changelog a human readable changelog fragment suitable for the .changes file
+func (d TreeDiff) Changelog() string {
+	s := fmt.Sprintf("- Update to %s %s (from %s):\n", d.New.Name, d.New.Version, d.Old.Version)
+	if d.Old.License != d.New.License {
+		s += fmt.Sprintf("  * License changed: %s -> %s\n", d.Old.License, d.New.License)
+	}
+	if len(d.Modules) == 0 && len(d.Scripts) == 0 {
+		return s + "  * No changes of the bundled modules\n"
+	}
+	for _, c := range d.Modules {
+		switch c.Kind() {
+		case "added":
+			s += fmt.Sprintf("  * Added bundled %s %s (%s)\n", c.Name, strings.Join(versionsOf(c.New), ", "), strings.Join(licensesOf(c.New), ", "))
+		case "removed":
+			s += fmt.Sprintf("  * Removed bundled %s %s\n", c.Name, strings.Join(versionsOf(c.Old), ", "))
+		case "upgraded":
+			s += fmt.Sprintf("  * Upgraded bundled %s %s -> %s\n", c.Name, c.Old[0].Version, c.New[0].Version)
+		case "downgraded":
+			s += fmt.Sprintf("  * Downgraded bundled %s %s -> %s\n", c.Name, c.Old[0].Version, c.New[0].Version)
+		case "changed":
+			s += fmt.Sprintf("  * Changed bundled %s %s -> %s\n", c.Name, strings.Join(versionsOf(c.Old), ", "), strings.Join(versionsOf(c.New), ", "))
+		}
+		if ol, nl := licensesOf(c.Old), licensesOf(c.New); len(c.Old) > 0 && len(c.New) > 0 && !equalStrings(ol, nl) {
+			s += fmt.Sprintf("  * License of %s changed: %s -> %s\n", c.Name, strings.Join(ol, ", "), strings.Join(nl, ", "))
+		}
+	}
+	for _, v := range d.Scripts {
+		s += fmt.Sprintf("  * New install scripts in %s %s: %s\n", v.Name, v.Version, strings.Join(v.InstallScripts, ", "))
+	}
+	return s
+}
+
+// groupByName group the bundled nodes of the tree by module name, versions
+// are deduplicated. The root module is left out, the diff compares it itself.
+func groupByName(tn TreeNode) map[string][]TreeNode {
+	m := map[string][]TreeNode{}
+	seen := map[string]struct{}{tn.Name + ":" + tn.Version: {}}
+	tn.Walk(func(v TreeNode) {
+		if _, ok := seen[v.Name+":"+v.Version]; ok {
+			return
+		}
+		seen[v.Name+":"+v.Version] = struct{}{}
+		m[v.Name] = append(m[v.Name], v)
+	})
+	for k := range m {
+		sort.Slice(m[k], func(i, j int) bool {
+			return semver.NewSemver(m[k][j].Version).GreaterThan(semver.NewSemver(m[k][i].Version))
+		})
+	}
+	return m
+}
+
+func versionsOf(a []TreeNode) []string {
+	s := []string{}
+	for _, v := range a {
+		s = append(s, v.Version)
+	}
+	return s
+}
+
+// licensesOf the unique licenses of the nodes, sorted
+func licensesOf(a []TreeNode) []string {
+	m := map[string]struct{}{}
+	for _, v := range a {
+		m[v.License] = struct{}{}
+	}
+	s := []string{}
+	for k := range m {
+		s = append(s, k)
+	}
+	sort.Strings(s)
+	return s
+}
+
+// equalStrings if two string slices are equal
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveTreeDocument resolve the dependency tree of a module version
+func resolveTreeDocument(pkg, ver string, exclusion Exclusion) TreeDocument {
+	temp := NewTempData()
+	temp.Exclusion = exclusion
+	tree := Tree{}
+	BuildDependencyTree(pkg, &ver, tree, ParentTree{}, Parents{}, temp)
+	temp.Problems.Check()
+	return tree.ToDocument(temp.Nodes)
+}
+
+// runDiff the "diff" subcommand, each side is either a version to resolve or a tree json
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	var pkg, oldVer, newVer, oldTree, newTree, exclude string
+	fs.StringVar(&pkg, "pkg", "", "the module to compare, required when resolving versions.")
+	fs.StringVar(&oldVer, "old", "", "the old version to resolve.")
+	fs.StringVar(&newVer, "new", "latest", "the new version to resolve.")
+	fs.StringVar(&oldTree, "old-tree", "", "the old dependency tree json, instead of resolving -old.")
+	fs.StringVar(&newTree, "new-tree", "", "the new dependency tree json, instead of resolving -new.")
+	fs.StringVar(&exclude, "exclude", "", "the module to be excluded when resolving, in 'rimraf:1.0.0,mkdirp:1.0.1' format.")
+	fs.Parse(args)
+
+	exclusion := Exclusion{}
+	if len(exclude) > 0 {
+		exclusion = parseExcludeString(exclude)
+	}
+
+	docs := []TreeDocument{}
+	for _, v := range [][2]string{{oldTree, oldVer}, {newTree, newVer}} {
+		switch {
+		case len(v[0]) > 0:
+			docs = append(docs, ReadTreeJson(v[0]))
+		case len(pkg) > 0 && len(v[1]) > 0:
+			docs = append(docs, resolveTreeDocument(pkg, v[1], exclusion))
+		default:
+			log.Fatal("You must specify a module with -pkg and -old, or a tree json with -old-tree, to compare.")
+		}
+	}
+
+	fmt.Print(NewTreeDiff(docs[0].Root, docs[1].Root).Changelog())
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func Test_Changelog(t *testing.T) {
+	o := TreeNode{Name: "har-validator", Version: "5.1.3", License: "MIT", Dependencies: []TreeNode{
+		{Name: "ajv", Version: "6.10.0", License: "MIT"},
+		{Name: "punycode", Version: "2.1.1", License: "MIT"},
+		{Name: "uri-js", Version: "4.2.2", License: "BSD-2-Clause"},
+	}}
+	n := TreeNode{Name: "har-validator", Version: "5.1.5", License: "MIT", Dependencies: []TreeNode{
+		{Name: "ajv", Version: "6.12.6", License: "MIT"},
+		{Name: "fast-deep-equal", Version: "3.1.3", License: "MIT", InstallScripts: []string{"postinstall"}},
+		{Name: "uri-js", Version: "4.4.1", License: "BSD-3-Clause"},
+	}}
+
+	answer := "- Update to har-validator 5.1.5 (from 5.1.3):\n" +
+		"  * Upgraded bundled ajv 6.10.0 -> 6.12.6\n" +
+		"  * Added bundled fast-deep-equal 3.1.3 (MIT)\n" +
+		"  * Removed bundled punycode 2.1.1\n" +
+		"  * Upgraded bundled uri-js 4.2.2 -> 4.4.1\n" +
+		"  * License of uri-js changed: BSD-2-Clause -> BSD-3-Clause\n" +
+		"  * New install scripts in fast-deep-equal 3.1.3: postinstall\n"
+	if s := NewTreeDiff(o, n).Changelog(); s == answer {
+		t.Log("Test TreeDiff.Changelog() passed")
+	} else {
+		t.Errorf("Test TreeDiff.Changelog() failed: expected\n%s\ngot\n%s", answer, s)
+	}
+
+	// the root module is compared by itself, not as a bundled module
+	o.Dependencies, n.Dependencies = nil, nil
+	n.License = "Apache-2.0"
+	answer = "- Update to har-validator 5.1.5 (from 5.1.3):\n" +
+		"  * License changed: MIT -> Apache-2.0\n" +
+		"  * No changes of the bundled modules\n"
+	if s := NewTreeDiff(o, n).Changelog(); s != answer {
+		t.Errorf("Test TreeDiff.Changelog() failed: expected\n%s\ngot\n%s", answer, s)
+	}
+}
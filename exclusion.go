@@ -2,65 +2,187 @@ package main
 
 import (
 	"fmt"
+	"log"
+	"path"
 	"regexp"
+	"sort"
 	"strings"
 
 	semver "github.com/openSUSE-zh/node-semver"
 )
 
 // Exclusion packages to be excluded. Useful for splitting a big bundle to several small bundles.
-type Exclusion map[string]string
+type Exclusion []*ExclusionRule
+
+// ExclusionRule a rule excluding modules from the bundle
+type ExclusionRule struct {
+	// Pattern module name or glob pattern, eg: "@types/*"
+	Pattern string
+	// Range semver range of the versions to exclude
+	Range string
+	// Reason why the modules are excluded
+	Reason string
+	// Requires if the excluded modules should become "Requires:" of the package,
+	// because they are packaged separately
+	Requires bool
+	// Matched "name:version" of the modules excluded by the rule
+	Matched map[string]struct{}
+	// Provided if the rule comes from the provides of the distribution. There
+	// is one for every module packaged there, they are only reported when
+	// they excluded something.
+	Provided bool
+}
 
 // Inspect debug output of Exclusion
 func (e Exclusion) Inspect() string {
 	s := "=== Packages to be excluded ===\n"
-	s += "|\tPackage    |    Version    |\n"
-	for k, v := range e {
-		s += fmt.Sprintf("|\t%s    |    %s    |\n", k, v)
+	s += "|\tPackage    |    Version    |    Requires    |    Reason\n"
+	provided := 0
+	for _, r := range e {
+		if r.Provided {
+			provided++
+			continue
+		}
+		s += fmt.Sprintf("|\t%s    |    %s    |    %t    |    %s\n", r.Pattern, r.Range, r.Requires, r.Reason)
+	}
+	if provided > 0 {
+		s += fmt.Sprintf("|\tand %d modules packaged in the distribution\n", provided)
 	}
 	s += "=== END ==="
 	return s
 }
 
-// Contains if a package with specified version locates in the Exclusion
+// Contains if a package with specified version locates in the Exclusion,
+// the matching rule records the package
 func (e Exclusion) Contains(k string, v semver.Semver) bool {
-	for m, n := range e {
-		if k != m {
+	return e.Exclude(k, v) != nil
+}
+
+// Exclude the rule excluding a package with specified version, nil if none.
+// The matching rule records the package
+func (e Exclusion) Exclude(k string, v semver.Semver) *ExclusionRule {
+	r := e.Match(k, v)
+	if r != nil {
+		r.Matched[k+":"+v.String()] = struct{}{}
+	}
+	return r
+}
+
+// Match the first rule matching a package with specified version, nil if none
+func (e Exclusion) Match(k string, v semver.Semver) *ExclusionRule {
+	for _, r := range e {
+		if ok, _ := path.Match(r.Pattern, k); !ok {
 			continue
 		}
 
-		c := semver.NewRange(n)
+		c := semver.NewRange(r.Range)
 
 		if c.Satisfy(v) {
-			return true
+			return r
+		}
+	}
+	return nil
+}
+
+// Unmatched rules excluding nothing, likely outdated. Rules of the
+// distribution provides are left out, most of them match nothing.
+func (e Exclusion) Unmatched() Exclusion {
+	a := Exclusion{}
+	for _, r := range e {
+		if len(r.Matched) == 0 && !r.Provided {
+			a = append(a, r)
+		}
+	}
+	return a
+}
+
+// Report debug output of what every rule excluded and the rules matching nothing
+func (e Exclusion) Report() string {
+	s := "=== Excluded packages ===\n"
+	for _, r := range e {
+		matched := []string{}
+		for k := range r.Matched {
+			matched = append(matched, k)
+		}
+		sort.Strings(matched)
+		if len(matched) == 0 && r.Provided {
+			continue
+		}
+		if len(matched) == 0 {
+			s += fmt.Sprintf("|\t%s %s    |    matched nothing, the rule may be outdated\n", r.Pattern, r.Range)
+			continue
 		}
+		s += fmt.Sprintf("|\t%s %s    |    %s\n", r.Pattern, r.Range, strings.Join(matched, ", "))
 	}
-	return false
+	s += "=== END ==="
+	return s
+}
+
+// newExclusionRule create a rule, a bare version means exactly that version
+// and an empty one any version
+func newExclusionRule(pattern, ver, reason string, requires bool) *ExclusionRule {
+	if _, e := path.Match(pattern, ""); e != nil {
+		log.Fatalf("Malformed exclusion pattern %s: %s", pattern, e)
+	}
+	r := &ExclusionRule{pattern, ">= 0.0.0", reason, requires, map[string]struct{}{}, false}
+	ver = strings.TrimSpace(ver)
+	if len(ver) > 0 {
+		re := regexp.MustCompile(`^\d`)
+		if re.MatchString(ver) && !strings.Contains(ver, " ") {
+			r.Range = "= " + ver
+		} else {
+			// You can pass your own semver constriant
+			r.Range = ver
+		}
+	}
+	return r
 }
 
 func parseExcludeString(s string) Exclusion {
 	e := Exclusion{}
 	for _, v := range strings.Split(s, ",") {
 		pkg, ver := parsePackageWithExplicitVersion(v)
-		if len(ver) == 0 {
-			e[pkg] = ">= 0.0.0"
-		} else {
-			re := regexp.MustCompile(`^\d`)
-			if re.MatchString(ver) {
-				e[pkg] = "= " + ver
-			} else {
-				// You can pass your own semver constriant
-				e[pkg] = ver
-			}
-		}
+		// excluded modules are split to separate packages
+		e = append(e, newExclusionRule(pkg, ver, "", true))
 	}
 	return e
 }
 
 func parsePackageWithExplicitVersion(s string) (string, string) {
-	a := strings.Split(s, ":")
+	a := strings.SplitN(s, ":", 2)
 	if len(a) < 2 {
 		return a[0], ""
 	}
 	return a[0], a[1]
 }
+
+// ReadExclusionFile read an exclusion file, eg:
+//
+//	[[exclude]]
+//	name = "@types/*"
+//	reason = "type definitions are not needed at runtime"
+//
+//	[[exclude]]
+//	name = "punycode"
+//	version = "^2.1.0 || ^3.0.0"
+//	reason = "packaged as nodejs-punycode"
+//	requires = true
+func ReadExclusionFile(file string) Exclusion {
+	c := struct {
+		Exclude []struct {
+			Name     string `toml:"name"`
+			Version  string `toml:"version"`
+			Reason   string `toml:"reason"`
+			Requires bool   `toml:"requires"`
+		} `toml:"exclude"`
+	}{}
+	readTOML(file, "exclusion file", &c)
+	e := Exclusion{}
+	for i, t := range c.Exclude {
+		if len(t.Name) == 0 {
+			log.Fatalf("%s: exclusion rule %d has no name", file, i+1)
+		}
+		e = append(e, newExclusionRule(t.Name, t.Version, t.Reason, t.Requires))
+	}
+	return e
+}
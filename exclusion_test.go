@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	semver "github.com/openSUSE-zh/node-semver"
+)
+
+func Test_ReadExclusionFile(t *testing.T) {
+	f := filepath.Join(os.TempDir(), "node2rpm-exclusion.toml")
+	s := `[[exclude]]
+name = "@types/*"
+reason = "type definitions are not needed at runtime"
+
+[[exclude]]
+name = "punycode"
+version = "^1.4.0 || ^2.1.0"
+requires = true
+
+[[exclude]]
+name = "left-pad"
+`
+	ioutil.WriteFile(f, []byte(s), 0644)
+	defer os.Remove(f)
+
+	e := ReadExclusionFile(f)
+	if len(e) != 3 || !e[1].Requires || e[0].Requires || e[0].Range != ">= 0.0.0" {
+		t.Fatalf("Test ReadExclusionFile() failed, unexpected rules %v", e)
+	}
+	if !e.Contains("@types/node", semver.NewSemver("14.14.31")) {
+		t.Errorf("Test Exclusion.Contains() failed, @types/node should match @types/*")
+	}
+	if !e.Contains("punycode", semver.NewSemver("2.1.1")) || e.Contains("punycode", semver.NewSemver("3.0.0")) {
+		t.Errorf("Test Exclusion.Contains() failed, punycode should match ^2.1.0 only")
+	}
+	if unmatched := e.Unmatched(); len(unmatched) != 1 || unmatched[0].Pattern != "left-pad" {
+		t.Errorf("Test Exclusion.Unmatched() failed, got %v", unmatched)
+	}
+
+	// inline tables are TOML too
+	ioutil.WriteFile(f, []byte(`exclude = [{ name = "@types/*" }, { name = "punycode", version = "^2.1.0", requires = true }]`), 0644)
+	if e := ReadExclusionFile(f); len(e) != 2 || e[0].Pattern != "@types/*" || !e[1].Requires {
+		t.Errorf("Test ReadExclusionFile() failed, unexpected rules from inline tables %v", e)
+	}
+}
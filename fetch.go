@@ -0,0 +1,232 @@
+package main
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fetch statuses of a tarball
+const (
+	// FetchPresent an identical file is in the working directory already
+	FetchPresent = "present"
+	// FetchCached the file is copied from the cache
+	FetchCached = "cached"
+	// FetchDownloaded the file is downloaded from the registry
+	FetchDownloaded = "downloaded"
+)
+
+// checksumHashes hash functions of the integrity algorithms, strongest first
+var checksumHashes = []struct {
+	Name string
+	New  func() hash.Hash
+}{
+	{"sha512", sha512.New},
+	{"sha384", sha512.New384},
+	{"sha256", sha256.New},
+	{"sha1", sha1.New},
+}
+
+// Downloader download the body of an uri, replaceable in tests
+type Downloader func(uri string) ([]byte, error)
+
+// httpDownloader download with net/http, unlike getHttpBody it can be run
+// concurrently and returns errors instead of exiting
+func httpDownloader(uri string) ([]byte, error) {
+	resp, e := http.Get(uri)
+	if e != nil {
+		return nil, e
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", uri, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// FetchResult the result of fetching the tarball of a node
+type FetchResult struct {
+	Node   *Node
+	File   string
+	Status string
+	Err    error
+}
+
+// FetchTarballs download the tarball of every node into the working directory
+// with jobs downloads at a time. Every tarball is verified against the
+// dist.integrity or dist.shasum of the registry and kept in a content
+// addressable cache, files already present and identical are skipped. Bodies
+// already in the response cache are not downloaded again.
+func FetchTarballs(nodes Nodes, wd, cacheDir string, jobs int, cache ResponseCache, download Downloader) []FetchResult {
+	type job struct {
+		idx  int
+		node *Node
+		body []byte
+	}
+	queue := []job{}
+	seen := map[string]struct{}{}
+	for _, k := range nodes.Keys() {
+		n := nodes[k]
+		if len(n.Tarball) == 0 {
+			continue
+		}
+		if _, ok := seen[n.Tarball]; ok {
+			continue
+		}
+		seen[n.Tarball] = struct{}{}
+		// the response cache is a plain map, read it before going concurrent
+		queue = append(queue, job{len(queue), n, cache[n.Tarball]})
+	}
+
+	if jobs < 1 {
+		jobs = 1
+	}
+	results := make([]FetchResult, len(queue))
+	ch := make(chan job)
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range ch {
+				results[j.idx] = fetchTarball(j.node, j.body, wd, cacheDir, download)
+			}
+		}()
+	}
+	for _, j := range queue {
+		ch <- j
+	}
+	close(ch)
+	wg.Wait()
+	return results
+}
+
+// fetchTarball place the verified tarball of the node in the working directory
+func fetchTarball(n *Node, body []byte, wd, cacheDir string, download Downloader) FetchResult {
+	r := FetchResult{Node: n, File: filepath.Base(n.Tarball)}
+	sums := n.Checksums()
+	dest := filepath.Join(wd, r.File)
+
+	if b, e := ioutil.ReadFile(dest); e == nil && verifyChecksums(b, sums) == nil {
+		r.Status = FetchPresent
+		return r
+	}
+
+	cached := cachePath(cacheDir, sums)
+	if len(cached) > 0 {
+		if b, e := ioutil.ReadFile(cached); e == nil && verifyChecksums(b, sums) == nil {
+			r.Status = FetchCached
+			r.Err = writeFileAtomic(dest, b)
+			return r
+		}
+	}
+
+	r.Status = FetchDownloaded
+	if body == nil {
+		b, e := download(n.Tarball)
+		if e != nil {
+			r.Err = e
+			return r
+		}
+		body = b
+	}
+	if e := verifyChecksums(body, sums); e != nil {
+		r.Err = e
+		return r
+	}
+	if len(cached) > 0 {
+		if e := writeFileAtomic(cached, body); e != nil {
+			log.Printf("Can not cache %s: %s", r.File, e)
+		}
+	}
+	r.Err = writeFileAtomic(dest, body)
+	return r
+}
+
+// verifyChecksums compare the body with every known checksum, at least one
+// checksum must be known
+func verifyChecksums(body []byte, sums map[string]string) error {
+	verified := false
+	for _, v := range checksumHashes {
+		expected, ok := sums[v.Name]
+		if !ok {
+			continue
+		}
+		h := v.New()
+		h.Write(body)
+		if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+			return fmt.Errorf("%s mismatch, expected %s, got %s", v.Name, expected, actual)
+		}
+		verified = true
+	}
+	if !verified {
+		return fmt.Errorf("no integrity or shasum to verify against")
+	}
+	return nil
+}
+
+// cachePath the path of a tarball in the content addressable cache, by its
+// strongest checksum, eg: "<cacheDir>/sha512/ab/ab12...". Empty if the
+// tarball has no checksum or there's no cache.
+func cachePath(cacheDir string, sums map[string]string) string {
+	if len(cacheDir) == 0 {
+		return ""
+	}
+	for _, v := range checksumHashes {
+		if sum, ok := sums[v.Name]; ok && len(sum) > 2 {
+			return filepath.Join(cacheDir, v.Name, sum[:2], sum)
+		}
+	}
+	return ""
+}
+
+// writeFileAtomic write to a temporary file and rename it, so an interrupted
+// download never leaves a truncated tarball behind
+func writeFileAtomic(file string, b []byte) error {
+	if e := os.MkdirAll(filepath.Dir(file), 0755); e != nil {
+		return e
+	}
+	f, e := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file))
+	if e != nil {
+		return e
+	}
+	defer os.Remove(f.Name())
+	if _, e := f.Write(b); e != nil {
+		f.Close()
+		return e
+	}
+	if e := f.Close(); e != nil {
+		return e
+	}
+	if e := os.Chmod(f.Name(), 0644); e != nil {
+		return e
+	}
+	return os.Rename(f.Name(), file)
+}
+
+// FetchReport debug output of the fetched tarballs, and if any failed
+func FetchReport(results []FetchResult) (string, bool) {
+	counts := map[string]int{}
+	failed := false
+	s := "=== Fetched tarballs ===\n"
+	for _, r := range results {
+		if r.Err != nil {
+			failed = true
+			s += fmt.Sprintf("|\t%s    |    %s\n", r.File, r.Err)
+			continue
+		}
+		counts[r.Status]++
+	}
+	s += fmt.Sprintf("|\t%d downloaded, %d from cache, %d already present\n", counts[FetchDownloaded], counts[FetchCached], counts[FetchPresent])
+	s += "=== END ==="
+	return s, failed
+}
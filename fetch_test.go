@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func Test_FetchTarballs(t *testing.T) {
+	tmp, e := ioutil.TempDir("", "node2rpm")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(tmp)
+	wd := filepath.Join(tmp, "wd")
+	cacheDir := filepath.Join(tmp, "cache")
+	os.Mkdir(wd, 0755)
+
+	body := []byte("punycode tarball")
+	sum512 := sha512.Sum512(body)
+	sum1 := sha1.Sum(body)
+	nodes := Nodes{
+		"punycode:2.1.1": &Node{Name: "punycode", Version: "2.1.1",
+			Tarball:   "https://registry.npmjs.org/punycode/-/punycode-2.1.1.tgz",
+			Integrity: "sha512-" + base64.StdEncoding.EncodeToString(sum512[:]),
+			Shasum:    hex.EncodeToString(sum1[:])},
+		"evil:1.0.0": &Node{Name: "evil", Version: "1.0.0",
+			Tarball: "https://registry.npmjs.org/evil/-/evil-1.0.0.tgz",
+			Shasum:  hex.EncodeToString(sum1[:])},
+	}
+
+	var mu sync.Mutex
+	downloads := 0
+	download := func(uri string) ([]byte, error) {
+		mu.Lock()
+		downloads++
+		mu.Unlock()
+		if uri == nodes["evil:1.0.0"].Tarball {
+			return []byte("republished"), nil
+		}
+		return body, nil
+	}
+
+	results := FetchTarballs(nodes, wd, cacheDir, 4, ResponseCache{}, download)
+	status := map[string]FetchResult{}
+	for _, r := range results {
+		status[r.File] = r
+	}
+	if r := status["punycode-2.1.1.tgz"]; r.Err != nil || r.Status != FetchDownloaded {
+		t.Errorf("Test FetchTarballs() failed, punycode should be downloaded, got %s %v", r.Status, r.Err)
+	}
+	if r := status["evil-1.0.0.tgz"]; r.Err == nil {
+		t.Errorf("Test FetchTarballs() failed, evil doesn't match its shasum")
+	}
+	if _, e := os.Stat(filepath.Join(wd, "evil-1.0.0.tgz")); !os.IsNotExist(e) {
+		t.Errorf("Test FetchTarballs() failed, unverified tarballs should not be written")
+	}
+	if _, failed := FetchReport(results); !failed {
+		t.Errorf("Test FetchReport() failed, a failure should be reported")
+	}
+
+	delete(nodes, "evil:1.0.0")
+	results = FetchTarballs(nodes, wd, cacheDir, 4, ResponseCache{}, download)
+	if results[0].Status != FetchPresent || downloads != 2 {
+		t.Errorf("Test FetchTarballs() failed, an identical file should be skipped, got %s", results[0].Status)
+	}
+
+	os.Remove(filepath.Join(wd, "punycode-2.1.1.tgz"))
+	results = FetchTarballs(nodes, wd, cacheDir, 4, ResponseCache{}, func(string) ([]byte, error) {
+		return nil, errors.New("offline")
+	})
+	if results[0].Err != nil || results[0].Status != FetchCached {
+		t.Errorf("Test FetchTarballs() failed, punycode should come from the cache, got %s %v", results[0].Status, results[0].Err)
+	}
+}
@@ -3,6 +3,7 @@ module github.com/openSUSE/node2rpm
 go 1.17
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/bitly/go-simplejson v0.5.0
 	github.com/openSUSE-zh/node-semver v0.0.0-20200214151914-1018a6bff0ca
 )
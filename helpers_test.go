@@ -0,0 +1,27 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"sort"
+)
+
+// npmTarball a gzipped tarball with the files in "package/", like npm packs them
+func npmTarball(files map[string]string) []byte {
+	names := []string{}
+	for k := range files {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, k := range names {
+		tw.WriteHeader(&tar.Header{Name: "package/" + k, Mode: 0644, Size: int64(len(files[k])), Typeflag: tar.TypeReg})
+		tw.Write([]byte(files[k]))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"log"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/bitly/go-simplejson"
@@ -17,31 +19,34 @@ func (licenses Licenses) Append(license string) {
 	}
 }
 
-// String convert license map to RPM License string
+// String convert license map to RPM License string. Licenses are parsed as
+// SPDX expressions and combined with AND, a dual licensed module stays a choice.
 func (licenses Licenses) String() string {
-	m := map[string]struct{}{}
+	keys := []string{}
 	for license := range licenses {
-		if license == "Unlicense" {
+		keys = append(keys, license)
+	}
+	sort.Strings(keys)
+
+	exprs := []*LicenseExpression{}
+	for _, license := range keys {
+		// missing and UNLICENSED ones are reported by LicensesReport
+		if len(license) == 0 || isUnlicensed(license) {
 			continue
 		}
-		if strings.Contains(license, " OR ") {
-			for _, s := range strings.Split(license, " OR ") {
-				if _, ok := m[s]; !ok {
-					m[s] = struct{}{}
-				}
-			}
-		} else {
-			if _, ok := m[license]; !ok {
-				m[license] = struct{}{}
-			}
+		expr, e := ParseLicenseExpression(license)
+		if e != nil {
+			log.Printf("Can not parse license expression, used as is: %s", e)
+			expr = &LicenseExpression{License: strings.Replace(license, " ", "-", -1)}
 		}
+		exprs = append(exprs, expr)
 	}
-	keys := reflect.ValueOf(m).MapKeys()
-	strKeys := make([]string, len(keys))
-	for i := 0; i < len(keys); i++ {
-		strKeys[i] = keys[i].String()
+
+	expr := combineLicenses(exprs)
+	if expr == nil {
+		return ""
 	}
-	return strings.Join(strKeys, " AND ")
+	return expr.String()
 }
 
 // getLicense parse license for package
@@ -52,14 +57,11 @@ func (licenses Licenses) String() string {
 // Both 2 and 3 are now deprecated but still in use.
 func getLicense(js *simplejson.Json) string {
 	j := js.Get("license")
-	r := strings.NewReplacer("(", "", ")", "")
 
 	s, e := j.String()
 	if e == nil {
-		if !strings.Contains(s, " OR ") {
-			s = strings.Replace(s, " ", "-", -1)
-		}
-		return r.Replace(s)
+		// free-form names like "Apache 2.0" are mapped by NormalizeLicense
+		return strings.TrimSpace(s)
 	}
 
 	m, e := j.Map()
@@ -82,3 +84,46 @@ func getLicense(js *simplejson.Json) string {
 
 	return ""
 }
+
+// isUnlicensed if the license is npm's "UNLICENSED", a proprietary module
+// nobody is allowed to use. Not to be confused with the "Unlicense".
+func isUnlicensed(license string) bool {
+	return strings.EqualFold(license, "UNLICENSED")
+}
+
+// seeLicenseFile the file of a "SEE LICENSE IN <file>" license, empty if the
+// license is something else
+func seeLicenseFile(license string) string {
+	prefix := "SEE LICENSE IN "
+	if len(license) > len(prefix) && strings.EqualFold(license[:len(prefix)], prefix) {
+		return strings.TrimSpace(license[len(prefix):])
+	}
+	return ""
+}
+
+// LicensesReport the license problems of the tree with their owning modules:
+// UNLICENSED modules, modules without license and licenses which can not be
+// mapped to SPDX ids. fatal means the bundle can not be distributed at all.
+// Empty if every module has a known license.
+func LicensesReport(nodes Nodes) (string, bool) {
+	s := ""
+	fatal := false
+	for _, k := range nodes.Keys() {
+		n := nodes[k]
+		switch {
+		case isUnlicensed(n.License):
+			s += "|\t" + n.Name + "    |    " + n.Version + "    |    UNLICENSED, proprietary and not distributable\n"
+			fatal = true
+		case len(n.License) == 0:
+			s += "|\t" + n.Name + "    |    " + n.Version + "    |    missing\n"
+		default:
+			for _, v := range n.UnknownLicenses {
+				s += "|\t" + n.Name + "    |    " + n.Version + "    |    " + v + " is not on the openSUSE license list\n"
+			}
+		}
+	}
+	if len(s) == 0 {
+		return "", false
+	}
+	return "=== License problems ===\n|\tPackage    |    Version    |    Problem\n" + s + "=== END ===", fatal
+}
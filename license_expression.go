@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LicenseExpression an SPDX license expression, either a leaf license with
+// an optional exception, eg: "GPL-2.0-only WITH Classpath-exception-2.0",
+// or a compound of operands joined by Op, "AND" or "OR".
+type LicenseExpression struct {
+	Op        string
+	License   string
+	Exception string
+	Operands  []*LicenseExpression
+}
+
+// ParseLicenseExpression parse an SPDX license expression into its AST.
+// WITH binds tighter than AND, AND binds tighter than OR.
+func ParseLicenseExpression(s string) (*LicenseExpression, error) {
+	p := licenseParser{tokens: tokenizeLicense(s)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty license expression")
+	}
+	expr, e := p.parseOr()
+	if e != nil {
+		return nil, fmt.Errorf("%s: %s", s, e)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%s: unexpected %q", s, p.tokens[p.pos])
+	}
+	return expr, nil
+}
+
+// tokenizeLicense split an expression into parentheses, operators and licenses.
+// Operators are upper cased, npm has seen "mit or apache-2.0" too.
+func tokenizeLicense(s string) []string {
+	r := strings.NewReplacer("(", " ( ", ")", " ) ")
+	tokens := strings.Fields(r.Replace(s))
+	for i, v := range tokens {
+		switch strings.ToUpper(v) {
+		case "AND", "OR", "WITH":
+			tokens[i] = strings.ToUpper(v)
+		}
+	}
+	return tokens
+}
+
+type licenseParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *licenseParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *licenseParser) parseOr() (*LicenseExpression, error) {
+	return p.parseCompound("OR", p.parseAnd)
+}
+
+func (p *licenseParser) parseAnd() (*LicenseExpression, error) {
+	return p.parseCompound("AND", p.parseWith)
+}
+
+// parseCompound parse operands produced by next joined by op
+func (p *licenseParser) parseCompound(op string, next func() (*LicenseExpression, error)) (*LicenseExpression, error) {
+	expr, e := next()
+	if e != nil {
+		return nil, e
+	}
+	operands := []*LicenseExpression{expr}
+	for p.peek() == op {
+		p.pos++
+		expr, e = next()
+		if e != nil {
+			return nil, e
+		}
+		operands = append(operands, expr)
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return &LicenseExpression{Op: op, Operands: operands}, nil
+}
+
+func (p *licenseParser) parseWith() (*LicenseExpression, error) {
+	t := p.peek()
+	switch t {
+	case "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case "(":
+		p.pos++
+		expr, e := p.parseOr()
+		if e != nil {
+			return nil, e
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return expr, nil
+	case ")", "AND", "OR", "WITH":
+		return nil, fmt.Errorf("unexpected %q", t)
+	}
+	p.pos++
+	expr := &LicenseExpression{License: t}
+	if p.peek() == "WITH" {
+		p.pos++
+		exception := p.peek()
+		switch exception {
+		case "", "(", ")", "AND", "OR", "WITH":
+			return nil, fmt.Errorf("missing exception after WITH %s", t)
+		}
+		p.pos++
+		expr.Exception = exception
+	}
+	return expr, nil
+}
+
+// IsLeaf if the expression is a single license, with or without an exception
+func (expr LicenseExpression) IsLeaf() bool {
+	return len(expr.Op) == 0
+}
+
+// Leaves all licenses of the expression, eg: ["MIT", "Apache-2.0"]
+func (expr *LicenseExpression) Leaves() []*LicenseExpression {
+	if expr.IsLeaf() {
+		return []*LicenseExpression{expr}
+	}
+	a := []*LicenseExpression{}
+	for _, v := range expr.Operands {
+		a = append(a, v.Leaves()...)
+	}
+	return a
+}
+
+// String render the expression, compound operands are parenthesized
+func (expr LicenseExpression) String() string {
+	if expr.IsLeaf() {
+		if len(expr.Exception) > 0 {
+			return expr.License + " WITH " + expr.Exception
+		}
+		return expr.License
+	}
+	a := []string{}
+	for _, v := range expr.Operands {
+		if v.IsLeaf() {
+			a = append(a, v.String())
+		} else {
+			a = append(a, "("+v.String()+")")
+		}
+	}
+	return strings.Join(a, " "+expr.Op+" ")
+}
+
+// Simplify flatten nested compounds of the same operator, drop duplicated
+// operands, drop "A OR B" next to "A" in a conjunction (absorption) and sort
+// the operands so the same licenses always render the same.
+func (expr *LicenseExpression) Simplify() *LicenseExpression {
+	if expr.IsLeaf() {
+		return expr
+	}
+
+	operands := []*LicenseExpression{}
+	for _, v := range expr.Operands {
+		v = v.Simplify()
+		if v.Op == expr.Op {
+			operands = append(operands, v.Operands...)
+		} else {
+			operands = append(operands, v)
+		}
+	}
+
+	seen := map[string]struct{}{}
+	unique := []*LicenseExpression{}
+	for _, v := range operands {
+		if _, ok := seen[v.String()]; !ok {
+			seen[v.String()] = struct{}{}
+			unique = append(unique, v)
+		}
+	}
+
+	if expr.Op == "AND" {
+		absorbed := []*LicenseExpression{}
+		for _, v := range unique {
+			if v.Op == "OR" && v.hasOperandIn(seen) {
+				continue
+			}
+			absorbed = append(absorbed, v)
+		}
+		unique = absorbed
+	}
+
+	sort.SliceStable(unique, func(i, j int) bool {
+		// leaves first, then alphabetically
+		if unique[i].IsLeaf() != unique[j].IsLeaf() {
+			return unique[i].IsLeaf()
+		}
+		return strings.ToLower(unique[i].String()) < strings.ToLower(unique[j].String())
+	})
+
+	if len(unique) == 1 {
+		return unique[0]
+	}
+	return &LicenseExpression{Op: expr.Op, Operands: unique}
+}
+
+// hasOperandIn if one of the operands renders to one of the strings in m
+func (expr LicenseExpression) hasOperandIn(m map[string]struct{}) bool {
+	for _, v := range expr.Operands {
+		if _, ok := m[v.String()]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// combineLicenses AND all expressions together, every bundled module's
+// license applies to the bundle
+func combineLicenses(exprs []*LicenseExpression) *LicenseExpression {
+	if len(exprs) == 0 {
+		return nil
+	}
+	return (&LicenseExpression{Op: "AND", Operands: exprs}).Simplify()
+}
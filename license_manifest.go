@@ -0,0 +1,100 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// licenseFileRe files of a module holding its license text, eg: LICENSE,
+// LICENSE.md, LICENCE-MIT, COPYING, MIT-LICENSE.txt
+var licenseFileRe = regexp.MustCompile(`(?i)^((licen[cs]e|copying|notice|unlicense)([.-][a-z0-9.-]*)?|[a-z0-9.]+-licen[cs]e(\.(md|txt|markdown))?)$`)
+
+// BundledLicense a bundled module installed somewhere in the tree and its license
+type BundledLicense struct {
+	Name    string
+	Version string
+	License string
+	// Files license files of the module relative to the root module's install
+	// directory, "package.json" if the license is only in the metadata
+	Files []string
+}
+
+// LicenseManifest the licenses of every bundled module, the content of LICENSES.bundled
+type LicenseManifest []BundledLicense
+
+// NewLicenseManifest collect the licenses and the license files of every
+// module in the tree. Modules installed in several places are listed for
+// each of them, their license files are installed several times.
+func NewLicenseManifest(doc TreeDocument, nodes Nodes, cache ResponseCache) LicenseManifest {
+	m := LicenseManifest{}
+	doc.Root.Walk(func(tn TreeNode) {
+		bl := BundledLicense{Name: tn.Name, Version: tn.Version, License: tn.License}
+		if n, ok := nodes[tn.Name+":"+tn.Version]; ok && len(n.License) > 0 {
+			bl.License = n.License
+		}
+		if len(tn.Resolved) > 0 {
+			files, e := listTarballFiles(getHttpBody(tn.Resolved, cache))
+			if e != nil {
+				log.Printf("%s %s: can not list tarball %s: %s", tn.Name, tn.Version, tn.Resolved, e)
+			}
+			for _, f := range files {
+				if strings.Contains(f, "/") || !licenseFileRe.MatchString(f) {
+					continue
+				}
+				if len(tn.Path) > 0 {
+					f = tn.Path + "/" + f
+				}
+				bl.Files = append(bl.Files, f)
+			}
+		}
+		sort.Strings(bl.Files)
+		m = append(m, bl)
+	})
+	sort.SliceStable(m, func(i, j int) bool {
+		if m[i].Name != m[j].Name {
+			return m[i].Name < m[j].Name
+		}
+		return m[i].Version < m[j].Version
+	})
+	return m
+}
+
+// Files the license files of all modules
+func (m LicenseManifest) Files() []string {
+	a := []string{}
+	for _, v := range m {
+		a = append(a, v.Files...)
+	}
+	return a
+}
+
+// String the LICENSES.bundled content, one module per line
+func (m LicenseManifest) String() string {
+	s := "# Licenses of the modules bundled in this package, generated by node2rpm\n"
+	s += "# module\tversion\tlicense\tsource\n"
+	for _, v := range m {
+		license := v.License
+		if len(license) == 0 {
+			license = "NOASSERTION"
+		}
+		source := "package.json"
+		if len(v.Files) > 0 {
+			source = strings.Join(v.Files, " ")
+		}
+		s += v.Name + "\t" + v.Version + "\t" + license + "\t" + source + "\n"
+	}
+	return s
+}
+
+// Save write LICENSES.bundled to the osc working directory
+func (m LicenseManifest) Save(wd string) string {
+	file := "LICENSES.bundled"
+	if e := ioutil.WriteFile(filepath.Join(wd, file), []byte(m.String()), 0644); e != nil {
+		log.Fatalf("Can not write license manifest %s: %s", file, e)
+	}
+	return file
+}
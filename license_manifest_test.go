@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_NewLicenseManifest(t *testing.T) {
+	uri := "https://registry.npmjs.org/punycode/-/punycode-2.1.1.tgz"
+	cache := ResponseCache{uri: npmTarball(map[string]string{"LICENSE-MIT": "x", "index.js": "x", "lib/LICENSE": "x", "README.md": "x"})}
+	doc := TreeDocument{Root: TreeNode{Name: "har-validator", Version: "5.1.3", License: "MIT", Dependencies: []TreeNode{
+		{Name: "punycode", Version: "2.1.1", Path: "node_modules/punycode", Resolved: uri},
+	}}}
+	nodes := Nodes{"punycode:2.1.1": &Node{Name: "punycode", Version: "2.1.1", License: "MIT"}}
+
+	m := NewLicenseManifest(doc, nodes, cache)
+	if files := m.Files(); !equalStrings(files, []string{"node_modules/punycode/LICENSE-MIT"}) {
+		t.Errorf("Test NewLicenseManifest() failed, unexpected license files %v", files)
+	}
+	s := m.String()
+	for _, v := range []string{"har-validator\t5.1.3\tMIT\tpackage.json\n", "punycode\t2.1.1\tMIT\tnode_modules/punycode/LICENSE-MIT\n"} {
+		if !strings.Contains(s, v) {
+			t.Errorf("Test LicenseManifest.String() failed, %q not found in\n%s", v, s)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// knownLicenses SPDX license ids found in npm modules which are on the
+// openSUSE license list, plus the openSUSE specific ones
+var knownLicenses = []string{
+	"0BSD", "AAL", "AFL-2.1", "AFL-3.0", "AGPL-1.0-only", "AGPL-1.0-or-later",
+	"AGPL-3.0-only", "AGPL-3.0-or-later", "Apache-1.1", "Apache-2.0",
+	"APSL-2.0", "Artistic-1.0", "Artistic-1.0-Perl", "Artistic-2.0",
+	"Beerware", "BlueOak-1.0.0", "BSD-1-Clause", "BSD-2-Clause",
+	"BSD-2-Clause-Patent", "BSD-3-Clause", "BSD-3-Clause-Clear", "BSD-4-Clause",
+	"BSD-Source-Code", "BSL-1.0", "bzip2-1.0.6", "CAL-1.0", "CC-BY-1.0",
+	"CC-BY-2.0", "CC-BY-2.5", "CC-BY-3.0", "CC-BY-4.0", "CC-BY-NC-2.0",
+	"CC-BY-NC-3.0", "CC-BY-NC-4.0", "CC-BY-NC-SA-4.0", "CC-BY-ND-4.0",
+	"CC-BY-SA-2.0", "CC-BY-SA-3.0", "CC-BY-SA-4.0", "CC0-1.0", "CDDL-1.0",
+	"CDDL-1.1", "CECILL-2.1", "CPAL-1.0", "CPL-1.0", "ECL-2.0", "EFL-2.0",
+	"EPL-1.0", "EPL-2.0", "EUPL-1.1", "EUPL-1.2", "GFDL-1.3-only",
+	"GFDL-1.3-or-later", "GPL-1.0-only", "GPL-1.0-or-later", "GPL-2.0-only",
+	"GPL-2.0-or-later", "GPL-3.0-only", "GPL-3.0-or-later", "HPND", "ICU",
+	"IPA", "ISC", "JSON", "LGPL-2.0-only", "LGPL-2.0-or-later",
+	"LGPL-2.1-only", "LGPL-2.1-or-later", "LGPL-3.0-only", "LGPL-3.0-or-later",
+	"LiLiQ-R-1.1", "MirOS", "MIT", "MIT-0", "MIT-CMU", "MPL-1.0", "MPL-1.1",
+	"MPL-2.0", "MPL-2.0-no-copyleft-exception", "MS-PL", "MS-RL", "MulanPSL-2.0",
+	"NCSA", "ODbL-1.0", "ODC-By-1.0", "OFL-1.0", "OFL-1.1", "OpenSSL",
+	"OSL-3.0", "PDDL-1.0", "PHP-3.01", "PostgreSQL", "PSF-2.0", "Python-2.0",
+	"Ruby", "SGI-B-2.0", "SSPL-1.0", "Unicode-DFS-2015", "Unicode-DFS-2016",
+	"Unicode-TOU", "Unlicense", "UPL-1.0", "Vim", "W3C", "W3C-20150513",
+	"WTFPL", "X11", "Zlib", "ZPL-2.0", "ZPL-2.1",
+	"SUSE-Public-Domain",
+}
+
+// knownExceptions SPDX license exceptions used after WITH
+var knownExceptions = []string{
+	"Autoconf-exception-3.0", "Bison-exception-2.2", "Classpath-exception-2.0",
+	"eCos-exception-2.0", "Font-exception-2.0", "GCC-exception-3.1", "LLVM-exception",
+	"OpenJDK-assembly-exception-1.0", "openvpn-openssl-exception",
+}
+
+// licenseAliases free-form license strings seen in npm modules, indexed by
+// their normalized key (see licenseKey), and the SPDX ids they mean
+var licenseAliases = map[string]string{
+	"apache":          "Apache-2.0",
+	"apache2":         "Apache-2.0",
+	"apache20":        "Apache-2.0",
+	"asl2":            "Apache-2.0",
+	"asl20":           "Apache-2.0",
+	"bsd":             "BSD-2-Clause",
+	"bsd2":            "BSD-2-Clause",
+	"bsd2clause":      "BSD-2-Clause",
+	"simplifiedbsd":   "BSD-2-Clause",
+	"freebsd":         "BSD-2-Clause",
+	"bsd3":            "BSD-3-Clause",
+	"newbsd":          "BSD-3-Clause",
+	"modifiedbsd":     "BSD-3-Clause",
+	"revisedbsd":      "BSD-3-Clause",
+	"bsd4":            "BSD-4-Clause",
+	"cc0":             "CC0-1.0",
+	"cc010":           "CC0-1.0",
+	"gpl":             "GPL-2.0-or-later",
+	"gpl2":            "GPL-2.0-only",
+	"gpl20":           "GPL-2.0-only",
+	"gpl2+":           "GPL-2.0-or-later",
+	"gpl20+":          "GPL-2.0-or-later",
+	"gpl3":            "GPL-3.0-only",
+	"gpl30":           "GPL-3.0-only",
+	"gpl3+":           "GPL-3.0-or-later",
+	"gpl30+":          "GPL-3.0-or-later",
+	"lgpl":            "LGPL-2.1-or-later",
+	"lgpl2":           "LGPL-2.0-only",
+	"lgpl21":          "LGPL-2.1-only",
+	"lgpl21+":         "LGPL-2.1-or-later",
+	"lgpl3":           "LGPL-3.0-only",
+	"lgpl30":          "LGPL-3.0-only",
+	"lgpl3+":          "LGPL-3.0-or-later",
+	"lgpl30+":         "LGPL-3.0-or-later",
+	"agpl":            "AGPL-3.0-or-later",
+	"agpl3":           "AGPL-3.0-only",
+	"agpl30":          "AGPL-3.0-only",
+	"mit":             "MIT",
+	"mitx11":          "MIT",
+	"expat":           "MIT",
+	"x11":             "X11",
+	"isc":             "ISC",
+	"iscl":            "ISC",
+	"mpl":             "MPL-2.0",
+	"mpl2":            "MPL-2.0",
+	"mpl20":           "MPL-2.0",
+	"mozillapublic20": "MPL-2.0",
+	"eclipsepublic10": "EPL-1.0",
+	"eclipsepublic20": "EPL-2.0",
+	"artistic2":       "Artistic-2.0",
+	"boost":           "BSL-1.0",
+	"wtfpl":           "WTFPL",
+	"wtfpl2":          "WTFPL",
+	"zlib":            "Zlib",
+	"python":          "Python-2.0",
+	"unlicense":       "Unlicense",
+	"publicdomain":    "SUSE-Public-Domain",
+	"pd":              "SUSE-Public-Domain",
+}
+
+// deprecatedLicenses deprecated SPDX ids and their current replacements
+var deprecatedLicenses = map[string]string{
+	"AGPL-1.0": "AGPL-1.0-only", "AGPL-3.0": "AGPL-3.0-only",
+	"GPL-1.0": "GPL-1.0-only", "GPL-1.0+": "GPL-1.0-or-later",
+	"GPL-2.0": "GPL-2.0-only", "GPL-2.0+": "GPL-2.0-or-later",
+	"GPL-3.0": "GPL-3.0-only", "GPL-3.0+": "GPL-3.0-or-later",
+	"LGPL-2.0": "LGPL-2.0-only", "LGPL-2.0+": "LGPL-2.0-or-later",
+	"LGPL-2.1": "LGPL-2.1-only", "LGPL-2.1+": "LGPL-2.1-or-later",
+	"LGPL-3.0": "LGPL-3.0-only", "LGPL-3.0+": "LGPL-3.0-or-later",
+	"GFDL-1.3": "GFDL-1.3-only", "eCos-2.0": "GPL-2.0-or-later WITH eCos-exception-2.0",
+	"GPL-2.0-with-classpath-exception": "GPL-2.0-only WITH Classpath-exception-2.0",
+	"GPL-3.0-with-GCC-exception":       "GPL-3.0-only WITH GCC-exception-3.1",
+}
+
+// licenseIndex knownLicenses and licenseAliases indexed by their normalized key
+var licenseIndex = func() map[string]string {
+	m := map[string]string{}
+	for _, v := range knownLicenses {
+		m[licenseKey(v)] = v
+	}
+	for k, v := range licenseAliases {
+		m[k] = v
+	}
+	return m
+}()
+
+var (
+	// licenseFillerRe words of license names not telling the license apart
+	licenseFillerRe = regexp.MustCompile(`\b(the|licen[sc]es?|version|ver)\b`)
+	// licenseVersionRe a "v" before the version, eg: "GPLv2"
+	licenseVersionRe = regexp.MustCompile(`v(\d)`)
+	// licensePunctRe anything but letters, digits and "+"
+	licensePunctRe = regexp.MustCompile(`[^a-z0-9+]`)
+	// licenseDigitsRe anything but digits and "+"
+	licenseDigitsRe = regexp.MustCompile(`[^0-9+]`)
+	// licenseIDRe strings shaped like SPDX ids, eg: "NPL-1.1", "CC-BY-ND-3.0"
+	licenseIDRe = regexp.MustCompile(`^[A-Za-z0-9]+(-[A-Za-z0-9.]+)+\+?$`)
+)
+
+// licenseKey normalize a free-form license string for matching: lower cased,
+// filler words and punctuation removed, "v2" to "2", eg: "Apache License, Version 2.0"
+// to "apache20", "GPLv2+" to "gpl2+"
+func licenseKey(s string) string {
+	s = strings.ToLower(s)
+	s = licenseFillerRe.ReplaceAllString(s, "")
+	s = licenseVersionRe.ReplaceAllString(s, "$1")
+	s = strings.Replace(s, "or later", "+", -1)
+	return licensePunctRe.ReplaceAllString(s, "")
+}
+
+// NormalizeLicense map the licenses of a license string to the SPDX ids
+// accepted by openSUSE. It returns the normalized expression and the
+// licenses which can not be mapped, those are kept as is.
+func NormalizeLicense(s string) (string, []string) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return s, nil
+	}
+	// free-form strings like "Apache License, Version 2.0" are no expressions
+	expr, e := ParseLicenseExpression(s)
+	if e != nil || (expr.IsLeaf() && len(expr.Exception) == 0) {
+		if id, ok := normalizeLicenseID(s); ok {
+			return id, nil
+		}
+		return s, []string{s}
+	}
+	unknown := []string{}
+	for _, leaf := range expr.Leaves() {
+		if len(leaf.Exception) > 0 {
+			exception, ok := normalizeException(leaf.Exception)
+			if !ok {
+				unknown = append(unknown, leaf.Exception)
+			}
+			leaf.Exception = exception
+		}
+		id, ok := normalizeLicenseID(leaf.License)
+		if !ok {
+			unknown = append(unknown, leaf.License)
+			continue
+		}
+		leaf.License = id
+	}
+	// a replacement may be an expression itself
+	if normalized, e := ParseLicenseExpression(expr.String()); e == nil {
+		expr = normalized
+	}
+	return expr.String(), unknown
+}
+
+// normalizeLicenseID map a single license to its SPDX id
+func normalizeLicenseID(s string) (string, bool) {
+	if v, ok := deprecatedLicenses[s]; ok {
+		return v, true
+	}
+	for _, v := range knownLicenses {
+		if strings.EqualFold(v, s) {
+			return v, true
+		}
+	}
+	if strings.HasPrefix(s, "LicenseRef-") {
+		return s, true
+	}
+	// "+" is the SPDX "or later" operator
+	if strings.HasSuffix(s, "+") {
+		for _, v := range knownLicenses {
+			if strings.EqualFold(v, strings.TrimSuffix(s, "+")) {
+				return v + "+", true
+			}
+		}
+	}
+
+	k := licenseKey(s)
+	if len(k) == 0 {
+		return s, false
+	}
+	if v, ok := licenseIndex[k]; ok {
+		return v, true
+	}
+	// an SPDX id not on the list is another license, not a typo of a
+	// listed one: "NPL-1.1" is no "MPL-1.1"
+	if licenseIDRe.MatchString(s) {
+		return s, false
+	}
+	if v, ok := fuzzyLicense(k); ok {
+		return v, true
+	}
+	return s, false
+}
+
+// normalizeException map a license exception to its SPDX id, case insensitive.
+// Unknown exceptions are kept as is
+func normalizeException(s string) (string, bool) {
+	for _, v := range knownExceptions {
+		if strings.EqualFold(v, s) {
+			return v, true
+		}
+	}
+	return s, false
+}
+
+// fuzzyLicense find the only license whose key is within a small edit
+// distance of k, one edit for short keys and two for long ones. Keys
+// differing in digits never match, "gpl2" is not "gpl3".
+func fuzzyLicense(k string) (string, bool) {
+	max := 1
+	if len(k) >= 10 {
+		max = 2
+	}
+	if len(k) < 4 {
+		return "", false
+	}
+	match := ""
+	for key, v := range licenseIndex {
+		if licenseDigitsRe.ReplaceAllString(key, "") != licenseDigitsRe.ReplaceAllString(k, "") {
+			continue
+		}
+		if levenshtein(key, k) <= max {
+			if len(match) > 0 && match != v {
+				// ambiguous
+				return "", false
+			}
+			match = v
+		}
+	}
+	return match, len(match) > 0
+}
+
+// levenshtein the edit distance of two strings
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
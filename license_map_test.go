@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func Test_NormalizeLicense(t *testing.T) {
+	cases := map[string]string{
+		"Apache 2.0":                  "Apache-2.0",
+		"Apache License, Version 2.0": "Apache-2.0",
+		"BSD":                         "BSD-2-Clause",
+		"GPLv2":                       "GPL-2.0-only",
+		"MIT/X11":                     "MIT",
+		"GPL-2.0+":                    "GPL-2.0-or-later",
+		"mit":                         "MIT",
+		"Apach License 2.0":           "Apache-2.0",
+		"(MIT OR GPL-3.0) AND BSD":    "(MIT OR GPL-3.0-only) AND BSD-2-Clause",
+		"Public Domain":               "SUSE-Public-Domain",
+	}
+	for k, v := range cases {
+		s, unknown := NormalizeLicense(k)
+		if s == v && len(unknown) == 0 {
+			t.Logf("Test NormalizeLicense() succeed, expected %s, got %s", v, s)
+		} else {
+			t.Errorf("Test NormalizeLicense() with %s failed, expected %s, got %s, unknown %v", k, v, s, unknown)
+		}
+	}
+
+	s, unknown := NormalizeLicense("MIT OR Custom-Corp-Terms")
+	if s != "MIT OR Custom-Corp-Terms" || len(unknown) != 1 || unknown[0] != "Custom-Corp-Terms" {
+		t.Errorf("Test NormalizeLicense() failed, expected Custom-Corp-Terms to be unknown, got %s %v", s, unknown)
+	}
+	if s, _ := NormalizeLicense("GPL-3.0"); s == "GPL-2.0-only" {
+		t.Errorf("Test NormalizeLicense() failed, fuzzy matching changed the version")
+	}
+	// SPDX ids which are not listed are no typos of listed ones
+	for _, v := range []string{"NPL-1.1", "ECL-1.0", "CC-BY-ND-3.0", "CC-BY-ND-2.0", "Apach-2.0"} {
+		if s, unknown := NormalizeLicense(v); s != v || len(unknown) != 1 {
+			t.Errorf("Test NormalizeLicense() with %s failed, expected it unknown, got %s %v", v, s, unknown)
+		}
+	}
+	s, unknown = NormalizeLicense("EPL-1.0 WITH foo")
+	if s != "EPL-1.0 WITH foo" || len(unknown) != 1 || unknown[0] != "foo" {
+		t.Errorf("Test NormalizeLicense() failed, expected the exception foo to be unknown, got %s %v", s, unknown)
+	}
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/bitly/go-simplejson"
@@ -23,3 +26,88 @@ func Test_getLicense(t *testing.T) {
 		}
 	}
 }
+
+func Test_ParseLicenseExpression(t *testing.T) {
+	cases := []string{"MIT", "(MIT OR Apache-2.0) AND BSD-3-Clause", "GPL-2.0-only WITH Classpath-exception-2.0",
+		"MIT OR Apache-2.0 AND ISC", "((MIT))", "mit or isc"}
+	answers := []string{"MIT", "(MIT OR Apache-2.0) AND BSD-3-Clause", "GPL-2.0-only WITH Classpath-exception-2.0",
+		"MIT OR (Apache-2.0 AND ISC)", "MIT", "mit OR isc"}
+	for i, v := range cases {
+		expr, e := ParseLicenseExpression(v)
+		if e != nil {
+			t.Errorf("Test ParseLicenseExpression() failed to parse %s: %s", v, e)
+			continue
+		}
+		if expr.String() == answers[i] {
+			t.Logf("Test ParseLicenseExpression() succeed, expected %s, got %s", answers[i], expr.String())
+		} else {
+			t.Errorf("Test ParseLicenseExpression() failed, expected %s, got %s", answers[i], expr.String())
+		}
+	}
+
+	for _, v := range []string{"", "MIT OR", "(MIT", "MIT WITH", "MIT Apache-2.0"} {
+		if _, e := ParseLicenseExpression(v); e == nil {
+			t.Errorf("Test ParseLicenseExpression() failed, %q should not be parsed", v)
+		}
+	}
+}
+
+func Test_LicensesString(t *testing.T) {
+	licenses := Licenses{}
+	for _, v := range []string{"MIT", "MIT OR Apache-2.0", "(BSD-3-Clause OR MIT) AND ISC", "ISC", "Apache-2.0 OR GPL-2.0-only WITH Classpath-exception-2.0"} {
+		licenses.Append(v)
+	}
+	answer := "ISC AND MIT AND (Apache-2.0 OR GPL-2.0-only WITH Classpath-exception-2.0)"
+	if s := licenses.String(); s == answer {
+		t.Log("Test Licenses.String() passed")
+	} else {
+		t.Errorf("Test Licenses.String() failed, expected %s, got %s", answer, s)
+	}
+}
+
+func Test_LicensesReport(t *testing.T) {
+	nodes := Nodes{}
+	nodes["a:1.0.0"] = &Node{Name: "a", Version: "1.0.0", License: "Unlicense"}
+	nodes["b:1.0.0"] = &Node{Name: "b", Version: "1.0.0"}
+	report, fatal := LicensesReport(nodes)
+	if fatal || !strings.Contains(report, "b    |    1.0.0    |    missing") || strings.Contains(report, "a    |") {
+		t.Errorf("Test LicensesReport() failed, got fatal %v\n%s", fatal, report)
+	}
+	nodes["c:1.0.0"] = &Node{Name: "c", Version: "1.0.0", License: "UNLICENSED"}
+	if _, fatal := LicensesReport(nodes); !fatal {
+		t.Errorf("Test LicensesReport() failed, UNLICENSED should be fatal")
+	}
+	if s := (Licenses{"Unlicense": {}, "": {}}).String(); s != "Unlicense" {
+		t.Errorf("Test Licenses.String() failed, expected Unlicense, got %s", s)
+	}
+}
+
+func Test_identifyLicenseText(t *testing.T) {
+	cases := map[string]string{
+		"MIT License\n\nCopyright (c) 2020 someone\n\nPermission is hereby granted, free of charge, to any person obtaining a copy\nof this software and associated documentation files (the \"Software\"), to deal\nin the Software without restriction, subject to the following conditions:\n\nThe above copyright notice and this permission notice shall be included in all\ncopies or substantial portions of the Software.": "MIT",
+		"ISC License\n\nPermission to use, copy, modify, and/or distribute this software for any\npurpose with or without fee is hereby granted, provided that the above\ncopyright notice and this permission notice appear in all copies.":                                                                                                                                                                                       "ISC",
+		"Proprietary, all rights reserved.": "",
+	}
+	for k, v := range cases {
+		if id, _ := identifyLicenseText([]byte(k)); id != v {
+			t.Errorf("Test identifyLicenseText() failed, expected %q, got %q", v, id)
+		}
+	}
+	// the full GNU texts name each other, eg: GPL-3 names the AGPL and the LGPL
+	gnu := map[string]string{"GPL-2": "GPL-2.0-only", "GPL-3": "GPL-3.0-only", "LGPL-2.1": "LGPL-2.1-only", "LGPL-3": "LGPL-3.0-only"}
+	for f, v := range gnu {
+		b, e := ioutil.ReadFile(filepath.Join("testdata", "licenses", f))
+		if e != nil {
+			t.Fatal(e)
+		}
+		if id, _ := identifyLicenseText(b); id != v {
+			t.Errorf("Test identifyLicenseText() failed, expected %q for the %s text, got %q", v, f, id)
+		}
+		if id, _ := identifyLicenseText(append([]byte("Copyright (C) 2020 someone\n\n"), b...)); id != v {
+			t.Errorf("Test identifyLicenseText() failed, expected %q for the %s text below a copyright, got %q", v, f, id)
+		}
+	}
+	if f := seeLicenseFile("SEE LICENSE IN LICENSE.md"); f != "LICENSE.md" {
+		t.Errorf("Test seeLicenseFile() failed, got %s", f)
+	}
+}
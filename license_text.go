@@ -0,0 +1,81 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// licenseText phrases identifying a license text, all of them must be found
+type licenseText struct {
+	ID      string
+	Phrases []string
+	// Title the heading the text opens with, for texts naming other
+	// licenses, eg: the GPL-3.0 text names the AGPL-3.0 and the LGPL
+	Title string
+}
+
+// licenseTitleWindow how far into the text its title may be found, leaving
+// room for a copyright line above it
+const licenseTitleWindow = 200
+
+// licenseTexts the license texts known, more specific ones go first:
+// the BSD-3-Clause text contains the whole BSD-2-Clause text, the ISC
+// text the whole 0BSD text.
+var licenseTexts = []licenseText{
+	{"Apache-2.0", []string{"apache license", "version 2.0"}, ""},
+	{"MPL-2.0", []string{"mozilla public license", "version 2.0"}, ""},
+	{"AGPL-3.0-only", nil, "gnu affero general public license version 3,"},
+	{"LGPL-3.0-only", nil, "gnu lesser general public license version 3,"},
+	{"LGPL-2.1-only", nil, "gnu lesser general public license version 2.1,"},
+	{"GPL-3.0-only", nil, "gnu general public license version 3,"},
+	{"GPL-2.0-only", nil, "gnu general public license version 2,"},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}, ""},
+	{"CC0-1.0", []string{"cc0 1.0 universal"}, ""},
+	{"WTFPL", []string{"do what the fuck you want to public license"}, ""},
+	{"BlueOak-1.0.0", []string{"blue oak model license", "version 1.0.0"}, ""},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose with or without fee is hereby granted, provided that the above copyright notice and this permission notice appear in all copies"}, ""},
+	{"0BSD", []string{"permission to use, copy, modify, and/or distribute this software for any purpose with or without fee is hereby granted", "the software is provided \"as is\" and the author disclaims all warranties"}, ""},
+	{"MIT-0", []string{"permission is hereby granted, free of charge, to any person obtaining a copy", "without restriction", "the software is provided \"as is\""}, ""},
+	{"MIT", []string{"permission is hereby granted, free of charge, to any person obtaining a copy", "the above copyright notice and this permission notice shall be included in all copies or substantial portions of the software"}, ""},
+	{"BSD-4-Clause", []string{"redistribution and use in source and binary forms", "all advertising materials mentioning features or use of this software"}, ""},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name of"}, ""},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms", "redistributions of source code must retain the above copyright notice"}, ""},
+}
+
+// spaceRe runs of whitespaces
+var spaceRe = regexp.MustCompile(`\s+`)
+
+// normalizeLicenseText lower case the text and collapse whitespaces, line
+// breaks differ between copies of the same license
+func normalizeLicenseText(text []byte) string {
+	s := strings.ToLower(string(text))
+	s = strings.NewReplacer("“", "\"", "”", "\"", "‘", "'", "’", "'").Replace(s)
+	return strings.TrimSpace(spaceRe.ReplaceAllString(s, " "))
+}
+
+// identifyLicenseText find the SPDX id of a license text
+func identifyLicenseText(text []byte) (string, bool) {
+	s := normalizeLicenseText(text)
+	for _, v := range licenseTexts {
+		if len(v.Title) > 0 {
+			if i := strings.Index(s, v.Title); i < 0 || i > licenseTitleWindow {
+				continue
+			}
+		}
+		found := true
+		for _, p := range v.Phrases {
+			if !strings.Contains(s, p) {
+				found = false
+				break
+			}
+		}
+		if found {
+			// the MIT-0 text lacks the notice condition of MIT
+			if v.ID == "MIT-0" && strings.Contains(s, "subject to the following conditions") {
+				continue
+			}
+			return v.ID, true
+		}
+	}
+	return "", false
+}
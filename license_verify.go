@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bitly/go-simplejson"
+)
+
+// LicenseMismatch a module whose licenses disagree between the registry
+// metadata, the package.json in its tarball and its license files
+type LicenseMismatch struct {
+	Node *Node
+	// PackageJson the normalized license of the package.json in the tarball
+	PackageJson string
+	// Files license files of the tarball and the licenses their texts match,
+	// empty if the text matches no known license
+	Files map[string]string
+	// Problems what disagrees
+	Problems []string
+}
+
+// VerifyLicenses unpack the tarball of every node and compare the license of
+// the registry metadata with the package.json in the tarball and the license
+// texts. Only modules with problems are returned.
+func VerifyLicenses(nodes Nodes, cache ResponseCache) []LicenseMismatch {
+	mismatches := []LicenseMismatch{}
+	for _, k := range nodes.Keys() {
+		n := nodes[k]
+		if len(n.Tarball) == 0 {
+			continue
+		}
+		m := verifyLicense(n, getHttpBody(n.Tarball, cache))
+		if len(m.Problems) > 0 {
+			mismatches = append(mismatches, m)
+		}
+	}
+	return mismatches
+}
+
+// verifyLicense compare the licenses of a node with the content of its tarball
+func verifyLicense(n *Node, body []byte) LicenseMismatch {
+	m := LicenseMismatch{Node: n, Files: map[string]string{}}
+
+	b, e := readTarballFile(body, "package.json")
+	if e != nil {
+		m.Problems = append(m.Problems, "no package.json in tarball: "+e.Error())
+		return m
+	}
+	js, e := simplejson.NewJson(b)
+	if e != nil {
+		m.Problems = append(m.Problems, "malformed package.json in tarball: "+e.Error())
+		return m
+	}
+	m.PackageJson, _ = NormalizeLicense(getLicense(js))
+	// "SEE LICENSE IN <file>" has been resolved by its file already
+	if len(seeLicenseFile(m.PackageJson)) == 0 && !sameLicense(n.License, m.PackageJson) {
+		m.Problems = append(m.Problems, fmt.Sprintf("registry declares %q, package.json declares %q", n.License, m.PackageJson))
+	}
+
+	files, e := listTarballFiles(body)
+	if e != nil {
+		m.Problems = append(m.Problems, "can not list tarball: "+e.Error())
+		return m
+	}
+	declared := licenseFamilies(n.License)
+	for _, f := range files {
+		if strings.Contains(f, "/") || !licenseFileRe.MatchString(f) {
+			continue
+		}
+		text, e := readTarballFile(body, f)
+		if e != nil {
+			continue
+		}
+		id, ok := identifyLicenseText(text)
+		m.Files[f] = id
+		if !ok {
+			// NOTICE files and uncommon licenses, nothing to compare
+			continue
+		}
+		if _, ok := declared[licenseFamily(id)]; !ok {
+			m.Problems = append(m.Problems, fmt.Sprintf("%s is %s text, not declared in %q", f, id, n.License))
+		}
+	}
+	return m
+}
+
+// sameLicense if two license expressions are the same after simplification
+func sameLicense(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ea, e := ParseLicenseExpression(a)
+	if e != nil {
+		return false
+	}
+	eb, e := ParseLicenseExpression(b)
+	if e != nil {
+		return false
+	}
+	return ea.Simplify().String() == eb.Simplify().String()
+}
+
+// licenseFamily the license without "only" or "or later" suffix, the license
+// text of GPL-2.0-only and GPL-2.0-or-later is the same
+func licenseFamily(id string) string {
+	id = strings.TrimSuffix(id, "+")
+	id = strings.TrimSuffix(id, "-only")
+	return strings.TrimSuffix(id, "-or-later")
+}
+
+// licenseFamilies the license families of every license in the expression
+func licenseFamilies(license string) map[string]struct{} {
+	m := map[string]struct{}{}
+	expr, e := ParseLicenseExpression(license)
+	if e != nil {
+		return m
+	}
+	for _, v := range expr.Leaves() {
+		m[licenseFamily(v.License)] = struct{}{}
+	}
+	return m
+}
+
+// LicenseMismatchReport debug output of the mismatches
+func LicenseMismatchReport(mismatches []LicenseMismatch) string {
+	if len(mismatches) == 0 {
+		return ""
+	}
+	s := "=== License mismatches between registry and tarball ===\n"
+	for _, m := range mismatches {
+		s += "|\t" + m.Node.Name + " " + m.Node.Version + "\n"
+		for _, p := range m.Problems {
+			s += "|\t\t" + p + "\n"
+		}
+		files := []string{}
+		for f := range m.Files {
+			files = append(files, f)
+		}
+		sort.Strings(files)
+		for _, f := range files {
+			id := m.Files[f]
+			if len(id) == 0 {
+				id = "unidentified"
+			}
+			s += "|\t\t" + f + ": " + id + "\n"
+		}
+	}
+	s += "=== END ==="
+	return s
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_verifyLicense(t *testing.T) {
+	body := npmTarball(map[string]string{
+		"package.json": `{"name": "a", "license": "ISC"}`,
+		"LICENSE":      "Permission to use, copy, modify, and/or distribute this software for any purpose with or without fee is hereby granted, provided that the above copyright notice and this permission notice appear in all copies.",
+		"COPYING":      "GNU GENERAL PUBLIC LICENSE\nVersion 2, June 1991",
+	})
+
+	m := verifyLicense(&Node{Name: "a", Version: "1.0.0", License: "ISC OR GPL-2.0-or-later"}, body)
+	if len(m.Problems) != 1 || !strings.Contains(m.Problems[0], "package.json declares \"ISC\"") {
+		t.Errorf("Test verifyLicense() failed, unexpected problems %v", m.Problems)
+	}
+	if m.Files["LICENSE"] != "ISC" || m.Files["COPYING"] != "GPL-2.0-only" {
+		t.Errorf("Test verifyLicense() failed, unexpected license files %v", m.Files)
+	}
+
+	m = verifyLicense(&Node{Name: "a", Version: "1.0.0", License: "ISC"}, body)
+	if len(m.Problems) != 1 || !strings.Contains(m.Problems[0], "COPYING is GPL-2.0-only text") {
+		t.Errorf("Test verifyLicense() failed, unexpected problems %v", m.Problems)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// ScriptClass a common kind of lifecycle script
+type ScriptClass struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// Needed if the module is likely broken without the script
+	Needed bool
+	// Advice what to do in the spec instead
+	Advice string
+}
+
+// scriptClasses the lifecycle scripts we know, the first match wins
+var scriptClasses = []ScriptClass{
+	{"node-gyp", regexp.MustCompile(`\bnode-gyp\b`), true, "build the addon in %build with node-gyp"},
+	{"prebuild-install", regexp.MustCompile(`\b(prebuild-install|node-pre-gyp|prebuildify|node-gyp-build)\b`), true, "prebuilt binaries are downloaded, build from source in %build instead"},
+	{"husky", regexp.MustCompile(`\bhusky\b`), false, "git hooks for development, ignore"},
+	// core-js and friends print their funding message with require('./postinstall')
+	{"opencollective", regexp.MustCompile(`\b(opencollective|open-collective)|require\('\./postinstall'\)`), false, "funding message, ignore"},
+	{"patch-package", regexp.MustCompile(`\bpatch-package\b`), true, "apply the patches with %patch in %prep"},
+	{"installer", regexp.MustCompile(`\bnode (\./)?install\.js\b`), true, "an installer script, check what it fetches or builds"},
+}
+
+// LifecycleScript a lifecycle script of a bundled module, ignored by the RPM build
+type LifecycleScript struct {
+	Node    *Node
+	Phase   string
+	Command string
+	Class   ScriptClass
+}
+
+// DetectLifecycleScripts the install scripts of the nodes. A module the
+// registry flags with hasInstallScript but without scripts runs the implicit
+// "node-gyp rebuild" of its binding.gyp.
+func DetectLifecycleScripts(nodes Nodes) []LifecycleScript {
+	scripts := []LifecycleScript{}
+	for _, k := range nodes.Keys() {
+		n := nodes[k]
+		found := false
+		for _, v := range lifecycleScripts {
+			cmd, ok := n.Scripts[v]
+			if !ok {
+				continue
+			}
+			found = true
+			scripts = append(scripts, LifecycleScript{n, v, cmd, classifyScript(cmd)})
+		}
+		if !found && n.HasInstallScript {
+			cmd := "node-gyp rebuild"
+			scripts = append(scripts, LifecycleScript{n, "install", cmd, classifyScript(cmd)})
+		}
+	}
+	return scripts
+}
+
+// classifyScript the class of a script command, unknown scripts need review
+// and are considered needed
+func classifyScript(cmd string) ScriptClass {
+	for _, v := range scriptClasses {
+		if v.Pattern.MatchString(cmd) {
+			return v
+		}
+	}
+	return ScriptClass{"unknown", nil, true, "review what the script does"}
+}
+
+// LifecycleScriptReport debug output of the lifecycle scripts and how many
+// of them are likely needed
+func LifecycleScriptReport(scripts []LifecycleScript) (string, int) {
+	needed := 0
+	s := "=== Lifecycle scripts not run by the RPM build ===\n"
+	for _, v := range scripts {
+		mark := ""
+		if v.Class.Needed {
+			needed++
+			mark = "NEEDED "
+		}
+		s += fmt.Sprintf("|\t%s    |    %s    |    %s: %s    |    %s%s, %s\n", v.Node.Name, v.Node.Version, v.Phase, v.Command, mark, v.Class.Name, v.Class.Advice)
+	}
+	s += "=== END ==="
+	return s, needed
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_classifyScript(t *testing.T) {
+	tests := map[string]string{
+		"node-gyp rebuild":                                    "node-gyp",
+		"prebuild-install || node-gyp rebuild":                "node-gyp",
+		"prebuild-install -r napi":                            "prebuild-install",
+		"node-pre-gyp install --fallback-to-build":            "prebuild-install",
+		"husky install":                                       "husky",
+		"opencollective-postinstall || true":                  "opencollective",
+		"node -e \"try{require('./postinstall')}catch(e){}\"": "opencollective",
+		"node install.js":                                     "installer",
+		"node ./scripts/fetch-things.js":                      "unknown",
+	}
+	for k, v := range tests {
+		if c := classifyScript(k); c.Name != v {
+			t.Errorf("Test classifyScript() failed for %q, expected %s, got %s", k, v, c.Name)
+		}
+	}
+}
+
+func Test_DetectLifecycleScripts(t *testing.T) {
+	nodes := Nodes{
+		"core-js:3.9.1":   &Node{Name: "core-js", Version: "3.9.1", Scripts: map[string]string{"postinstall": "node -e \"try{require('./postinstall')}catch(e){}\""}},
+		"fsevents:1.2.13": &Node{Name: "fsevents", Version: "1.2.13", Scripts: map[string]string{}, HasInstallScript: true},
+		"punycode:2.1.1":  &Node{Name: "punycode", Version: "2.1.1", Scripts: map[string]string{}},
+	}
+	scripts := DetectLifecycleScripts(nodes)
+	if len(scripts) != 2 || scripts[1].Node.Name != "fsevents" || scripts[1].Class.Name != "node-gyp" {
+		t.Fatalf("Test DetectLifecycleScripts() failed, got %v", scripts)
+	}
+	report, needed := LifecycleScriptReport(scripts)
+	if needed != 1 || !strings.Contains(report, "NEEDED node-gyp") {
+		t.Errorf("Test LifecycleScriptReport() failed, expected fsevents needed, got\n%s", report)
+	}
+}
@@ -5,58 +5,292 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		runDiff(os.Args[2:])
+		return
+	}
+
 	currentWd, e := os.Getwd()
 	if e != nil {
 		log.Fatal(e)
 	}
 
 	var defaultSpecTemplatePath = currentWd + "/templates/node2rpm.template"
-	var pkg, ver, exclude, wd, specTemplate string
-	var bundle bool
+	var pkg, ver, exclude, excludeFile, provides, providesMode, cacheDir, backend, distroBins, wd, specTemplate, treeOut, licensePolicy string
+	var jobs int
+	var inspectBinaries, inspectNative, bundle, vendor, dryRun, fetch, serviceVerify, sbom, spdx, licenseManifest, verifyLicenses bool
 	flag.StringVar(&pkg, "pkg", "", "the module needs to package.")
 	flag.StringVar(&ver, "ver", "latest", "the module's version.")
 	flag.BoolVar(&bundle, "bundle", true, "don't bundle dependencies.")
 	flag.StringVar(&exclude, "exclude", "", "the module to be excluded, in 'rimraf:1.0.0,mkdirp:1.0.1' format.")
+	flag.StringVar(&excludeFile, "exclude-file", "", "the exclusion rules file, defaults to 'exclusion.toml' in the osc working directory if it exists.")
+	flag.StringVar(&provides, "provides", "", "the repodata primary.xml(.gz) or 'rpm -qa --provides' output of the distribution, modules packaged there are excluded and required.")
+	flag.StringVar(&providesMode, "provides-mode", ProvidesAll, "the Provides to emit: 'all' npm(module) and bundled(npm(...)) of every bundled module, 'bundled' only the latter when the nodejs-packaging dependency generator provides npm(module), 'none'.")
 	flag.StringVar(&wd, "wd", currentWd, "the osc working directory")
+	flag.BoolVar(&dryRun, "dry-run", false, "only print the obsolete sources to remove from the osc working directory.")
+	flag.BoolVar(&fetch, "fetch", false, "download and verify the tarballs into the osc working directory.")
+	flag.StringVar(&cacheDir, "cache-dir", "", "the content addressable tarball cache, defaults to 'node2rpm' in the user cache directory.")
+	flag.IntVar(&jobs, "jobs", 8, "the number of concurrent downloads.")
+	flag.BoolVar(&serviceVerify, "service-verify", false, "add a verify_file service checking the sha256 of every tarball to _service, the tarballs are downloaded to compute it.")
+	flag.BoolVar(&vendor, "vendor", false, "ship the bundled modules as one reproducible node_modules.tar.zst instead of their tarballs, needs zstd.")
+	flag.StringVar(&backend, "backend", BackendDownloadURL, "how OBS fetches the sources: 'download_url' a service for every tarball, 'obs_scm' the upstream repository plus the node_modules service reading the generated package-lock.json.")
+	flag.BoolVar(&inspectNative, "inspect-native", false, "also look for a binding.gyp in the tarballs of the modules whose package.json doesn't tell they are native addons.")
+	flag.BoolVar(&inspectBinaries, "inspect-binaries", false, "download the tarballs to look for prebuilt binaries, wasm blobs and minified-only sources in the tarballs, and remove the binaries in %prep.")
+	flag.StringVar(&distroBins, "distro-bins", "", "the executables of the distribution, a repodata primary.xml(.gz) or a list of /usr/bin paths, defaults to -provides. Bin names taken there are warned about.")
 	flag.StringVar(&specTemplate, "st", defaultSpecTemplatePath, "the spec template file")
+	flag.StringVar(&treeOut, "tree-out", "", "write the dependency tree json to this file, defaults to '<module>-<version>.json' in the osc working directory.")
+	flag.StringVar(&licensePolicy, "license-policy", "", "the license policy file, defaults to 'license-policy.toml' in the osc working directory if it exists.")
+	flag.BoolVar(&licenseManifest, "license-manifest", false, "generate LICENSES.bundled and %license lines for the license files of every bundled module, their tarballs are downloaded to find them.")
+	flag.BoolVar(&verifyLicenses, "verify-licenses", false, "download the tarballs and compare the licenses of the registry with their package.json and license files.")
+	flag.BoolVar(&sbom, "sbom", true, "generate a CycloneDX SBOM of the module and its bundled dependencies.")
+	flag.BoolVar(&spdx, "spdx", true, "generate an SPDX document of the module and its bundled dependencies.")
 	flag.Parse()
 
 	if len(pkg) == 0 {
 		log.Fatal("You must specify a module name to package.")
 	}
 
+	if providesMode != ProvidesAll && providesMode != ProvidesBundled && providesMode != ProvidesNone {
+		log.Fatalf("Unknown provides mode %s, use all, bundled or none.", providesMode)
+	}
+
+	if backend != BackendDownloadURL && backend != BackendObsScm {
+		log.Fatalf("Unknown backend %s, use download_url or obs_scm.", backend)
+	}
+	if backend == BackendObsScm && vendor {
+		log.Fatal("The obs_scm backend downloads the modules itself, it can not be vendored.")
+	}
+
 	temp := NewTempData()
 	spec := NewSpecfile(pkg, wd, specTemplate)
+	spec.ProvidesMode = providesMode
+	spec.Backend = backend
+	tree := Tree{}
 
 	if bundle {
 		if len(exclude) > 0 {
 			temp.Exclusion = parseExcludeString(exclude)
+		}
+		if len(excludeFile) == 0 {
+			if _, e := os.Stat(filepath.Join(wd, "exclusion.toml")); e == nil {
+				excludeFile = filepath.Join(wd, "exclusion.toml")
+			}
+		}
+		if len(excludeFile) > 0 {
+			temp.Exclusion = append(temp.Exclusion, ReadExclusionFile(excludeFile)...)
+		}
+		if len(provides) > 0 {
+			temp.Provides = ReadProvides(provides)
+			temp.Exclusion = append(temp.Exclusion, temp.Provides.ToExclusion()...)
+			log.Printf("%d modules are packaged in the distribution.", len(temp.Provides))
+		}
+		if len(temp.Exclusion) > 0 {
 			log.Println("These packages are set to be excluded:")
 			fmt.Println(temp.Exclusion.Inspect())
 		} else {
 			log.Println("No package to exclude, skipped.")
 		}
 
-		tree := Tree{}
 		parentTree := ParentTree{}
 		BuildDependencyTree(pkg, &ver, tree, parentTree, Parents{}, temp)
 		log.Printf("%s %s tree has been built:\n", pkg, ver)
 		fmt.Println(tree.Inspect(0))
-		tree.ToJson()
+		log.Printf("Dependency tree has been written to %s", tree.ToJson(treeOut, wd, temp.Nodes))
+		temp.Problems.FindCycles(temp.Nodes)
+		temp.Problems.Check()
+		if len(temp.Exclusion) > 0 {
+			fmt.Println(temp.Exclusion.Report())
+			if unmatched := temp.Exclusion.Unmatched(); len(unmatched) > 0 {
+				log.Printf("%d exclusion rules matched nothing, consider removing them.", len(unmatched))
+			}
+		}
 	} else {
 		pkg1 := RegistryQuery(pkg, temp.ResponseCache)
 		if ver == "latest" {
 			ver = pkg1.Versions[0].String()
 		}
-		temp.Licenses.Append(pkg1.License)
-		temp.Tarballs.Append(pkg1.Json.Get(ver).Get("dist").Get("tarball").MustString())
+		temp.AppendNode(pkg1, ver)
+		tree[pkg1.Name+":"+ver] = &Tree{}
+	}
+
+	if report, fatal := LicensesReport(temp.Nodes); len(report) > 0 {
+		log.Println("Some licenses are missing or not on the openSUSE license list, rpmlint and legal review will reject them:")
+		fmt.Println(report)
+		if fatal {
+			log.Fatal("UNLICENSED modules can not be packaged.")
+		}
+	}
+
+	if verifyLicenses {
+		if mismatches := VerifyLicenses(temp.Nodes, temp.ResponseCache); len(mismatches) > 0 {
+			log.Printf("%d modules have licenses differing from their tarballs, check them before submitting:", len(mismatches))
+			fmt.Println(LicenseMismatchReport(mismatches))
+		} else {
+			log.Println("Licenses of the registry match the tarballs.")
+		}
+	}
+
+	spec.Native = DetectNativeAddons(temp.Nodes, temp.ResponseCache, inspectNative)
+	if len(spec.Native) > 0 {
+		log.Printf("%d modules are native addons, the package can not be noarch:", len(spec.Native))
+		fmt.Println(NativeAddonReport(spec.Native))
+	}
+
+	if scripts := DetectLifecycleScripts(temp.Nodes); len(scripts) > 0 {
+		report, needed := LifecycleScriptReport(scripts)
+		log.Printf("%d modules have install scripts, the RPM build doesn't run them:", len(scripts))
+		fmt.Println(report)
+		if needed > 0 {
+			log.Printf("WARNING: %d install scripts are likely needed for their modules to work, handle them in the spec.", needed)
+		}
+	}
+
+	// the tree has the module as its only key, named as the registry knows
+	// it, which is not necessarily how it was given by -pkg
+	key := ""
+	for k := range tree {
+		key = k
+	}
+	root, ok := temp.Nodes[key]
+	if !ok {
+		log.Fatalf("%s %s is not among the resolved modules, check the -pkg and -ver given.", pkg, ver)
+	}
+	// the files are only needed for "directories.bin" and "directories.man"
+	var files []string
+	if len(root.BinDir) > 0 || len(root.ManDir) > 0 {
+		var e error
+		if files, e = listTarballFiles(getHttpBody(root.Tarball, temp.ResponseCache)); e != nil {
+			log.Printf("%s %s: can not list tarball %s, executables and man pages of its directories are skipped: %s", root.Name, root.Version, root.Tarball, e)
+		}
+	}
+	spec.Bins, spec.Mans = Executables(root, files), ManPages(root, files)
+	if len(spec.Bins) > 0 || len(spec.Mans) > 0 {
+		log.Printf("%s has %d executables and %d man pages.", pkg, len(spec.Bins), len(spec.Mans))
+	}
+	if len(distroBins) == 0 {
+		distroBins = provides
+	}
+	if len(distroBins) > 0 && len(spec.Bins) > 0 {
+		conflicts := BinConflicts(spec.Bins, ReadDistroBins(readMaybeGzip(distroBins)))
+		names := []string{}
+		for k := range conflicts {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			owner := conflicts[name]
+			if len(owner) == 0 {
+				owner = "another package"
+			}
+			log.Printf("WARNING: %%{_bindir}/%s conflicts with %s in the distribution, rename it or add Conflicts.", name, owner)
+		}
+	}
+
+	if inspectBinaries {
+		if inspections := InspectBinaries(tree.ToDocument(temp.Nodes), temp.Nodes, temp.ResponseCache); len(inspections) > 0 {
+			log.Printf("%d modules ship prebuilt binaries or only minified sources:", len(inspections))
+			fmt.Println(BinaryReport(inspections))
+			spec.Removals = PrepRemovals(inspections)
+			if len(spec.Removals) > 0 {
+				log.Printf("Suggested %%prep lines, added to the spec:\n%s", strings.Join(spec.Removals, "\n"))
+			}
+		}
+	}
+
+	if len(licensePolicy) == 0 {
+		if _, e := os.Stat(filepath.Join(wd, "license-policy.toml")); e == nil {
+			licensePolicy = filepath.Join(wd, "license-policy.toml")
+		}
+	}
+	if len(licensePolicy) > 0 {
+		policy := ReadLicensePolicy(licensePolicy)
+		if report, fatal := PolicyReport(policy.Check(key, temp.Nodes)); len(report) > 0 {
+			fmt.Println(report)
+			if fatal {
+				log.Fatalf("Some licenses are denied by the license policy %s, the spec and _service are not written.", licensePolicy)
+			}
+		}
+	}
+
+	if licenseManifest {
+		m := NewLicenseManifest(tree.ToDocument(temp.Nodes), temp.Nodes, temp.ResponseCache)
+		spec.AddLicenseArtifact(m.Save(wd))
+		spec.Licenses = m.Files()
+		log.Printf("License manifest with %d modules and %d license files has been written.", len(m), len(spec.Licenses))
+	}
+
+	if sbom {
+		bom := NewCycloneDX(key, temp.Nodes)
+		spec.AddArtifact(bom.Save(wd, pkg), "%{_datadir}/%{name}")
+		log.Printf("CycloneDX SBOM with %d components has been written.", len(bom.Components)+1)
+	}
+
+	if spdx {
+		doc := NewSPDX(key, temp.Nodes)
+		for _, v := range doc.Save(wd, pkg) {
+			spec.AddArtifact(v, "%{_datadir}/%{name}")
+		}
+		log.Printf("SPDX document with %d packages has been written.", len(doc.Packages))
+	}
+
+	// the modules whose tarballs are sources of the package
+	sources := temp.Nodes
+	if vendor && bundle {
+		archive, manifest := VendorModules(tree.ToDocument(temp.Nodes), temp.Nodes, temp.ResponseCache, wd, zstdCompressor)
+		log.Printf("Bundled modules have been vendored into %s, listed in %s.", archive, manifest)
+		// only the module itself is downloaded now
+		temp.Tarballs = Tarballs{}
+		temp.Tarballs.Append(root.Tarball, root.Integrity, root.Shasum)
+		spec.Vendored = []string{archive, manifest}
+		sources = Nodes{key: root}
+	}
+
+	var stale []string
+	if backend == BackendObsScm {
+		lock := NewPackageLock(tree.ToDocument(temp.Nodes), temp.Nodes)
+		log.Printf("%s with %d modules has been written.", lock.Save(wd), len(lock.Packages))
+		stale = ToScmService(wd, root, dryRun)
+		// the node_modules service downloads the tarballs
+		sources = Nodes{}
+	} else {
+		file, missing := temp.Tarballs.SaveChecksums(wd)
+		log.Printf("Checksums of the sources have been written to %s.", file)
+		if len(missing) > 0 {
+			log.Printf("%d tarballs have no sha512, only their sha1 shasum can be verified: %s", len(missing), strings.Join(missing, ", "))
+		}
+		if len(missing) < len(temp.Tarballs) {
+			spec.Checksums = filepath.Base(file)
+		}
+		if serviceVerify {
+			temp.Tarballs.HashSha256(temp.ResponseCache)
+		}
+		stale = temp.Tarballs.ToService(wd, serviceVerify, dryRun)
+	}
+	if len(stale) > 0 {
+		log.Printf("%d sources are obsolete:", len(stale))
+		RemoveSources(stale, wd, execRunner, dryRun)
+	}
+
+	if fetch {
+		if len(cacheDir) == 0 {
+			if dir, e := os.UserCacheDir(); e == nil {
+				cacheDir = filepath.Join(dir, "node2rpm")
+			}
+		}
+		report, failed := FetchReport(FetchTarballs(sources, wd, cacheDir, jobs, temp.ResponseCache, httpDownloader))
+		fmt.Println(report)
+		if failed {
+			log.Fatal("Some tarballs can not be fetched or don't match their integrity.")
+		}
 	}
 
-	temp.Tarballs.ToService(wd)
-	spec.Fill(pkg, ver, bundle, temp)
+	spec.Fill(root.Name, ver, bundle, temp)
 	spec.Save()
 
 	log.Printf("Congrats! Module %s has been created/updated.", pkg)
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// nativeDependencies modules only native addons depend on
+var nativeDependencies = []string{"node-gyp", "node-addon-api", "nan"}
+
+// nodeGypRe an install script building with node-gyp
+var nodeGypRe = regexp.MustCompile(`\bnode-gyp\s+(rebuild|build|configure)\b`)
+
+// nativeBuildRequires what building a native addon needs
+var nativeBuildRequires = []string{"gcc-c++", "nodejs-devel", "python3"}
+
+// NativeAddon a module compiling native code when installed, and why we think so
+type NativeAddon struct {
+	Node    *Node
+	Reasons []string
+}
+
+// DetectNativeAddons find the native addons among the nodes by their
+// package.json. npm publish sets gypfile and the install script when there's
+// a binding.gyp, so only with inspect the tarballs of the modules not
+// classified by it are downloaded to look for one.
+func DetectNativeAddons(nodes Nodes, cache ResponseCache, inspect bool) []NativeAddon {
+	addons := []NativeAddon{}
+	for _, k := range nodes.Keys() {
+		n := nodes[k]
+		reasons := nativeReasons(n, nil)
+		if len(reasons) == 0 && inspect && len(n.Tarball) > 0 {
+			// a broken tarball isn't our business here, VerifyLicenses reports it
+			files, _ := listTarballFiles(getHttpBody(n.Tarball, cache))
+			reasons = nativeReasons(n, files)
+		}
+		if len(reasons) > 0 {
+			addons = append(addons, NativeAddon{n, reasons})
+		}
+	}
+	return addons
+}
+
+// nativeReasons why the node is a native addon, empty if it isn't
+func nativeReasons(n *Node, files []string) []string {
+	reasons := []string{}
+	for _, f := range files {
+		if f == "binding.gyp" {
+			reasons = append(reasons, "has binding.gyp")
+			break
+		}
+	}
+	if n.Gypfile {
+		reasons = append(reasons, "gypfile: true")
+	}
+	for _, v := range nativeDependencies {
+		if _, ok := n.Ranges[v]; ok {
+			reasons = append(reasons, "depends on "+v)
+		}
+	}
+	for _, v := range lifecycleScripts {
+		if nodeGypRe.MatchString(n.Scripts[v]) {
+			reasons = append(reasons, v+" script runs "+nodeGypRe.FindString(n.Scripts[v]))
+		}
+	}
+	return reasons
+}
+
+// NativeAddonReport debug output of the native addons
+func NativeAddonReport(addons []NativeAddon) string {
+	s := "=== Native addons ===\n"
+	for _, v := range addons {
+		s += fmt.Sprintf("|\t%s    |    %s    |    %s\n", v.Node.Name, v.Node.Version, strings.Join(v.Reasons, ", "))
+	}
+	s += "=== END ==="
+	return s
+}
+
+// archLines the BuildArch or the native BuildRequires of the spec, a comment
+// lists the modules needing them
+func archLines(addons []NativeAddon) string {
+	if len(addons) == 0 {
+		return "BuildArch:" + padding("BuildArch") + "noarch"
+	}
+	names := []string{}
+	for _, v := range addons {
+		names = append(names, v.Node.Name)
+	}
+	s := "# native addons: " + strings.Join(names, ", ") + "\n"
+	for _, v := range nativeBuildRequires {
+		s += "BuildRequires:" + padding("BuildRequires") + v + "\n"
+	}
+	return strings.TrimSuffix(s, "\n")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_nativeReasons(t *testing.T) {
+	n := &Node{Name: "bcrypt", Version: "5.0.1", Gypfile: true,
+		Ranges:  map[string]string{"node-addon-api": "^3.1.0", "@mapbox/node-pre-gyp": "^1.0.0"},
+		Scripts: map[string]string{"install": "node-pre-gyp install --fallback-to-build || node-gyp rebuild"},
+	}
+	expected := []string{"has binding.gyp", "gypfile: true", "depends on node-addon-api", "install script runs node-gyp rebuild"}
+	if r := nativeReasons(n, []string{"package.json", "binding.gyp", "src/bcrypt.cc"}); !reflect.DeepEqual(r, expected) {
+		t.Errorf("Test nativeReasons() failed, expected %v, got %v", expected, r)
+	}
+	pure := &Node{Name: "punycode", Version: "2.1.1", Ranges: map[string]string{}, Scripts: map[string]string{"postinstall": "echo node-gyp is not run"}}
+	if r := nativeReasons(pure, []string{"package.json", "lib/binding.gyp"}); len(r) > 0 {
+		t.Errorf("Test nativeReasons() failed, punycode is pure js, got %v", r)
+	}
+}
+
+func Test_DetectNativeAddons(t *testing.T) {
+	uri := "https://registry.npmjs.org/a/-/a-1.0.0.tgz"
+	nodes := Nodes{
+		"a:1.0.0":      &Node{Name: "a", Version: "1.0.0", Tarball: uri},
+		"bcrypt:5.0.1": &Node{Name: "bcrypt", Version: "5.0.1", Gypfile: true, Tarball: "https://registry.npmjs.org/bcrypt/-/bcrypt-5.0.1.tgz"},
+	}
+	// only a is in the cache, bcrypt is known by its package.json
+	cache := ResponseCache{uri: npmTarball(map[string]string{"binding.gyp": "{}"})}
+	if addons := DetectNativeAddons(nodes, ResponseCache{}, false); len(addons) != 1 || addons[0].Node.Name != "bcrypt" {
+		t.Errorf("Test DetectNativeAddons() failed, expected bcrypt only without inspecting, got %v", addons)
+	}
+	if addons := DetectNativeAddons(nodes, cache, true); len(addons) != 2 || addons[0].Reasons[0] != "has binding.gyp" {
+		t.Errorf("Test DetectNativeAddons() failed, expected a and bcrypt, got %v", addons)
+	}
+}
+
+func Test_archLines(t *testing.T) {
+	if s := archLines(nil); s != "BuildArch:      noarch" {
+		t.Errorf("Test archLines() failed, expected noarch, got %q", s)
+	}
+	s := archLines([]NativeAddon{{&Node{Name: "bcrypt"}, []string{"gypfile: true"}}})
+	if strings.Contains(s, "noarch") || !strings.Contains(s, "# native addons: bcrypt") || !strings.Contains(s, "BuildRequires:  gcc-c++") {
+		t.Errorf("Test archLines() failed, got %q", s)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Node metadata of a resolved module, a "name:version" key in the Tree
+type Node struct {
+	Name         string
+	Version      string
+	License      string
+	Tarball      string
+	Integrity    string
+	Shasum       string
+	Deduped      bool
+	Dependencies []string
+	// UnknownLicenses licenses which can not be mapped to SPDX ids
+	UnknownLicenses []string
+	// LicenseFile the file in the tarball holding the license, "SEE LICENSE IN <file>"
+	LicenseFile string
+	// Scripts the lifecycle scripts run by npm when installing the module
+	Scripts map[string]string
+	// Ranges the dependencies declared in package.json, name to semver range
+	Ranges map[string]string
+	// Repository the source repository url of package.json
+	Repository string
+	// Gypfile if package.json has "gypfile": true, a native addon built by node-gyp
+	Gypfile bool
+	// HasInstallScript if the registry reports install scripts, including the
+	// implicit "node-gyp rebuild" of a binding.gyp
+	HasInstallScript bool
+	// Bin the executables of package.json, name to path in the module
+	Bin map[string]string
+	// Man the man pages of package.json, paths in the module
+	Man []string
+	// BinDir, ManDir "directories.bin" and "directories.man" of package.json,
+	// every file in them is an executable or a man page
+	BinDir string
+	ManDir string
+}
+
+// lifecycleScripts the scripts npm runs when installing a module
+var lifecycleScripts = []string{"preinstall", "install", "postinstall"}
+
+// Nodes holds metadata of all resolved modules, indexed by "name:version"
+type Nodes map[string]*Node
+
+// Append records a resolved version of a Package and returns its Node.
+// An already recorded Node is returned as is.
+func (nodes Nodes) Append(pkg Package, ver string) *Node {
+	k := pkg.Name + ":" + ver
+	if n, ok := nodes[k]; ok {
+		return n
+	}
+	js := pkg.Json.Get(ver)
+	dist := js.Get("dist")
+	n := &Node{
+		Name:             pkg.Name,
+		Version:          ver,
+		License:          getLicense(js),
+		Tarball:          dist.Get("tarball").MustString(),
+		Integrity:        dist.Get("integrity").MustString(),
+		Shasum:           dist.Get("shasum").MustString(),
+		Scripts:          map[string]string{},
+		Ranges:           map[string]string{},
+		Gypfile:          js.Get("gypfile").MustBool(),
+		HasInstallScript: js.Get("hasInstallScript").MustBool(),
+	}
+	// the license may change between versions, the package's one is the latest
+	if len(n.License) == 0 {
+		n.License = pkg.License
+	}
+	if file := seeLicenseFile(n.License); len(file) > 0 {
+		// resolved by TempData.AppendNode, it needs the tarball
+		n.LicenseFile = file
+	} else if !isUnlicensed(n.License) {
+		n.License, n.UnknownLicenses = NormalizeLicense(n.License)
+	}
+	scripts := js.Get("scripts")
+	for _, v := range lifecycleScripts {
+		if s, e := scripts.Get(v).String(); e == nil {
+			n.Scripts[v] = s
+		}
+	}
+	deps, _ := js.Get("dependencies").Map()
+	for name, v := range deps {
+		if r, ok := v.(string); ok {
+			n.Ranges[name] = r
+		}
+	}
+	// "bin" is either a path, named after the unscoped module, or a map
+	n.Bin = map[string]string{}
+	if bin, e := js.Get("bin").String(); e == nil {
+		n.Bin[path.Base(pkg.Name)] = bin
+	} else {
+		m, _ := js.Get("bin").Map()
+		for k, v := range m {
+			if s, ok := v.(string); ok {
+				n.Bin[k] = s
+			}
+		}
+	}
+	// "man" is either a path or an array
+	if man, e := js.Get("man").String(); e == nil {
+		n.Man = []string{man}
+	} else {
+		n.Man = js.Get("man").MustStringArray()
+	}
+	n.BinDir = js.Get("directories").Get("bin").MustString()
+	n.ManDir = js.Get("directories").Get("man").MustString()
+	// "repository" is either an url or {"type": "git", "url": "..."}
+	if repo, e := js.Get("repository").String(); e == nil {
+		n.Repository = repo
+	} else {
+		n.Repository = js.Get("repository").Get("url").MustString()
+	}
+	nodes[k] = n
+	return n
+}
+
+// Keys the sorted keys of Nodes, useful to produce reproducible output
+func (nodes Nodes) Keys() []string {
+	keys := make([]string, 0, len(nodes))
+	for k := range nodes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Path the shortest dependency path from the node root to the node k, eg:
+// ["har-validator:5.1.3", "ajv:6.12.6", "uri-js:4.4.1"]. nil if k is not
+// reachable from root.
+func (nodes Nodes) Path(root, k string) []string {
+	prev := map[string]string{root: ""}
+	queue := []string{root}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if cur == k {
+			path := []string{}
+			for v := k; len(v) > 0; v = prev[v] {
+				path = append([]string{v}, path...)
+			}
+			return path
+		}
+		n, ok := nodes[cur]
+		if !ok {
+			continue
+		}
+		for _, d := range n.Dependencies {
+			if _, seen := prev[d]; !seen {
+				prev[d] = cur
+				queue = append(queue, d)
+			}
+		}
+	}
+	return nil
+}
+
+// UUID a name based (version 5) uuid of the nodes, the same tree always gets
+// the same uuid so generated documents are reproducible
+func (nodes Nodes) UUID() string {
+	h := sha1.New()
+	for _, k := range nodes.Keys() {
+		h.Write([]byte(nodes[k].Purl() + "\n"))
+	}
+	b := h.Sum(nil)
+	b[6] = (b[6] & 0x0f) | 0x50
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Checksums the hex encoded checksums of the node's tarball indexed by
+// algorithm, eg: "sha512", "sha1". dist.integrity is a Subresource Integrity
+// string ("sha512-<base64>"), dist.shasum is the legacy sha1 hex digest.
+func (n Node) Checksums() map[string]string {
+	return Tarball{Integrity: n.Integrity, Shasum: n.Shasum}.Checksums()
+}
+
+// Purl the package url of the node, eg: "pkg:npm/%40types/node@14.14.31"
+func (n Node) Purl() string {
+	name := n.Name
+	if strings.HasPrefix(name, "@") {
+		name = "%40" + strings.TrimPrefix(name, "@")
+	}
+	return "pkg:npm/" + name + "@" + strings.Replace(n.Version, "+", "%2B", -1)
+}
+
+// resolveLicenseFile identify the license of a "SEE LICENSE IN <file>" node by
+// the file in its tarball
+func (n *Node) resolveLicenseFile(cache ResponseCache) {
+	text, e := readTarballFile(getHttpBody(n.Tarball, cache), n.LicenseFile)
+	if e != nil {
+		log.Printf("%s %s: can not read license file %s: %s", n.Name, n.Version, n.LicenseFile, e)
+		n.UnknownLicenses = []string{n.License}
+		return
+	}
+	id, ok := identifyLicenseText(text)
+	if !ok {
+		log.Printf("%s %s: can not identify license file %s", n.Name, n.Version, n.LicenseFile)
+		n.UnknownLicenses = []string{n.License}
+		return
+	}
+	log.Printf("%s %s: license file %s identified as %s", n.Name, n.Version, n.LicenseFile, id)
+	n.License = id
+}
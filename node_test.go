@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func Test_Purl(t *testing.T) {
+	cases := []Node{{Name: "punycode", Version: "2.1.1"}, {Name: "@types/node", Version: "14.14.31"}}
+	answers := []string{"pkg:npm/punycode@2.1.1", "pkg:npm/%40types/node@14.14.31"}
+	for i, n := range cases {
+		if purl := n.Purl(); purl == answers[i] {
+			t.Logf("Test Node.Purl() succeed, expected %s, got %s", answers[i], purl)
+		} else {
+			t.Errorf("Test Node.Purl() failed, expected %s, got %s", answers[i], purl)
+		}
+	}
+}
+
+func Test_Checksums(t *testing.T) {
+	n := Node{Name: "punycode", Version: "2.1.1",
+		Integrity: "sha512-XRsRjdf+j5ml+y/6GKHPZbrF/8p2Yga0JPtdqTIY2Xe5ohJPD9saDJJLPvp9+NSBprVvevdXZybnj2cv8OEd0A==",
+		Shasum:    "b58b010ac40c22c5657616c8d2c2c02c7bf479ec"}
+	m := n.Checksums()
+	sha512 := "5d1b118dd7fe8f99a5fb2ffa18a1cf65bac5ffca766206b424fb5da93218d977b9a2124f0fdb1a0c924b3efa7df8d481a6b56f7af7576726e78f672ff0e11dd0"
+	if m["sha512"] != sha512 {
+		t.Errorf("Test Node.Checksums() failed, expected sha512 %s, got %s", sha512, m["sha512"])
+	}
+	if m["sha1"] != n.Shasum {
+		t.Errorf("Test Node.Checksums() failed, expected sha1 %s, got %s", n.Shasum, m["sha1"])
+	}
+}
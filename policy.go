@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+)
+
+// Policy verdicts, ordered by severity
+const (
+	PolicyAllowed = iota
+	PolicyReview
+	PolicyDenied
+)
+
+// LicensePolicy the SPDX ids a distribution can ship in bundles. Ids may be
+// glob patterns, eg: "CC-BY-NC-*". Licenses not in Allow are denied unless
+// Allow is empty, Deny and Review take precedence over Allow.
+type LicensePolicy struct {
+	Allow  []string `toml:"allow"`
+	Deny   []string `toml:"deny"`
+	Review []string `toml:"review"`
+}
+
+// ReadLicensePolicy read a license policy file, eg:
+//
+//	allow = ["MIT", "ISC", "BSD-*", "Apache-2.0"]
+//	deny = ["SSPL-1.0", "CC-BY-NC-*"]
+//	review = ["GPL-3.0-only"]
+func ReadLicensePolicy(file string) LicensePolicy {
+	p := LicensePolicy{}
+	readTOML(file, "license policy", &p)
+	for _, v := range append(append(append([]string{}, p.Allow...), p.Deny...), p.Review...) {
+		if _, e := path.Match(v, ""); e != nil {
+			log.Fatalf("Malformed pattern %s in license policy %s: %s", v, file, e)
+		}
+	}
+	return p
+}
+
+// matchLicense if the license matches one of the patterns, case insensitive
+func matchLicense(patterns []string, license string) bool {
+	for _, v := range patterns {
+		if ok, _ := path.Match(strings.ToLower(v), strings.ToLower(license)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// verdictOf the verdict of a single license, with or without exception
+func (p LicensePolicy) verdictOf(expr *LicenseExpression) int {
+	license := expr.String()
+	switch {
+	case matchLicense(p.Deny, license) || matchLicense(p.Deny, expr.License):
+		return PolicyDenied
+	case matchLicense(p.Review, license) || matchLicense(p.Review, expr.License):
+		return PolicyReview
+	case len(p.Allow) == 0 || matchLicense(p.Allow, license) || matchLicense(p.Allow, expr.License):
+		return PolicyAllowed
+	}
+	return PolicyDenied
+}
+
+// Verdict the verdict of a license expression: a conjunction is as bad as
+// its worst operand, for a choice the best operand can be picked.
+func (p LicensePolicy) Verdict(expr *LicenseExpression) int {
+	if expr.IsLeaf() {
+		return p.verdictOf(expr)
+	}
+	verdict := PolicyDenied
+	if expr.Op == "AND" {
+		verdict = PolicyAllowed
+	}
+	for _, v := range expr.Operands {
+		o := p.Verdict(v)
+		if (expr.Op == "AND" && o > verdict) || (expr.Op == "OR" && o < verdict) {
+			verdict = o
+		}
+	}
+	return verdict
+}
+
+// PolicyViolation a module whose license is denied or needs review
+type PolicyViolation struct {
+	Node    *Node
+	Verdict int
+	// Path the dependency path from the root module to the module
+	Path []string
+}
+
+// Check check every node of the tree against the policy, root is the
+// "name:version" key of the module to package. Missing and unknown licenses
+// need review.
+func (p LicensePolicy) Check(root string, nodes Nodes) []PolicyViolation {
+	violations := []PolicyViolation{}
+	for _, k := range nodes.Keys() {
+		n := nodes[k]
+		verdict := PolicyReview
+		if expr, e := ParseLicenseExpression(n.License); e == nil && len(n.UnknownLicenses) == 0 {
+			verdict = p.Verdict(expr)
+		}
+		if verdict != PolicyAllowed {
+			violations = append(violations, PolicyViolation{n, verdict, nodes.Path(root, k)})
+		}
+	}
+	return violations
+}
+
+// PolicyReport debug output of the violations, fatal if any license is denied
+func PolicyReport(violations []PolicyViolation) (string, bool) {
+	if len(violations) == 0 {
+		return "", false
+	}
+	fatal := false
+	s := "=== License policy violations ===\n"
+	for _, v := range violations {
+		verdict := "needs review"
+		if v.Verdict == PolicyDenied {
+			verdict = "denied"
+			fatal = true
+		}
+		license := v.Node.License
+		if len(license) == 0 {
+			license = "missing license"
+		}
+		s += fmt.Sprintf("|\t%s %s    |    %s    |    %s\n", v.Node.Name, v.Node.Version, license, verdict)
+		s += "|\t\tvia " + strings.Join(v.Path, " -> ") + "\n"
+	}
+	s += "=== END ==="
+	return s, fatal
+}
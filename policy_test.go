@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func Test_Verdict(t *testing.T) {
+	p := LicensePolicy{Allow: []string{"MIT", "BSD-*", "Apache-2.0"}, Deny: []string{"SSPL-1.0", "CC-BY-NC-*"}, Review: []string{"GPL-3.0-only"}}
+	cases := map[string]int{
+		"MIT":                                 PolicyAllowed,
+		"BSD-3-Clause":                        PolicyAllowed,
+		"SSPL-1.0 OR MIT":                     PolicyAllowed,
+		"SSPL-1.0 AND MIT":                    PolicyDenied,
+		"CC-BY-NC-4.0":                        PolicyDenied,
+		"GPL-3.0-only AND Apache-2.0":         PolicyReview,
+		"ISC":                                 PolicyDenied,
+		"Apache-2.0 WITH LLVM-exception":      PolicyAllowed,
+		"GPL-3.0-only WITH GCC-exception-3.1": PolicyReview,
+		"SSPL-1.0 WITH Classpath-exception-2.0 OR ISC": PolicyDenied,
+	}
+	for k, v := range cases {
+		expr, _ := ParseLicenseExpression(k)
+		if verdict := p.Verdict(expr); verdict != v {
+			t.Errorf("Test LicensePolicy.Verdict() with %s failed, expected %d, got %d", k, v, verdict)
+		}
+	}
+}
+
+func Test_PolicyCheck(t *testing.T) {
+	nodes := Nodes{}
+	nodes["a:1.0.0"] = &Node{Name: "a", Version: "1.0.0", License: "MIT", Dependencies: []string{"b:1.0.0"}}
+	nodes["b:1.0.0"] = &Node{Name: "b", Version: "1.0.0", License: "MIT", Dependencies: []string{"c:1.0.0"}}
+	nodes["c:1.0.0"] = &Node{Name: "c", Version: "1.0.0", License: "SSPL-1.0"}
+	violations := LicensePolicy{Deny: []string{"SSPL-1.0"}}.Check("a:1.0.0", nodes)
+	if len(violations) != 1 || !equalStrings(violations[0].Path, []string{"a:1.0.0", "b:1.0.0", "c:1.0.0"}) {
+		t.Errorf("Test LicensePolicy.Check() failed, unexpected violations %v", violations)
+	}
+	if _, fatal := PolicyReport(violations); !fatal {
+		t.Errorf("Test PolicyReport() failed, denied license should be fatal")
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Problems problems found when resolving the dependency tree. They are
+// collected instead of aborting on the first one, to report them all at once.
+type Problems struct {
+	// Cycles dependency paths of modules depending on themselves, eg: [A, B, A]
+	Cycles [][]string
+	// Unsatisfiable constraints no version of the module matches
+	Unsatisfiable []Unsatisfiable
+}
+
+// Unsatisfiable a dependency constraint no version of the module matches
+type Unsatisfiable struct {
+	Dependent  string
+	Name       string
+	Constraint string
+	Versions   []string
+}
+
+// AppendCycle records a cycle, a cycle is recorded only once no matter from
+// which module of it it was entered
+func (p *Problems) AppendCycle(path []string) {
+	for _, v := range p.Cycles {
+		if sameCycle(v, path) {
+			return
+		}
+	}
+	p.Cycles = append(p.Cycles, path)
+}
+
+// FindCycles records a cycle for every edge leading back to a module on the
+// dependency path, searching the whole resolved graph. It runs after
+// resolution, as a cycle may close through a hoisted module which is not a
+// direct parent.
+func (p *Problems) FindCycles(nodes Nodes) {
+	// 1 on the current path, 2 done
+	state := map[string]int{}
+	path := []string{}
+	var visit func(k string)
+	visit = func(k string) {
+		state[k] = 1
+		path = append(path, k)
+		deps := append([]string{}, nodes[k].Dependencies...)
+		sort.Strings(deps)
+		for _, d := range deps {
+			if _, ok := nodes[d]; !ok {
+				continue
+			}
+			switch state[d] {
+			case 0:
+				visit(d)
+			case 1:
+				for i := len(path) - 1; i >= 0; i-- {
+					if path[i] == d {
+						p.AppendCycle(append(append([]string{}, path[i:]...), d))
+						break
+					}
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[k] = 2
+	}
+	for _, k := range nodes.Keys() {
+		if state[k] == 0 {
+			visit(k)
+		}
+	}
+}
+
+// sameCycle if two cycle paths consist of the same edges, eg: [A, B, A] and [B, A, B]
+func sameCycle(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	n := len(a) - 1
+	for i := 0; i < n; i++ {
+		if a[i] != b[0] {
+			continue
+		}
+		match := true
+		for j := 0; j < n; j++ {
+			if a[(i+j)%n] != b[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// Fatal if the tree has problems the package can not be built with
+func (p Problems) Fatal() bool {
+	return len(p.Unsatisfiable) > 0
+}
+
+// Empty if no problem was found
+func (p Problems) Empty() bool {
+	return len(p.Cycles) == 0 && len(p.Unsatisfiable) == 0
+}
+
+// Inspect debug output of Problems
+func (p Problems) Inspect() string {
+	s := "=== Problems of the dependency tree ===\n"
+	if len(p.Cycles) > 0 {
+		s += fmt.Sprintf("%d dependency cycles, npm tolerates them but they are worth knowing:\n", len(p.Cycles))
+		for _, v := range p.Cycles {
+			s += "|\t" + strings.Join(v, " -> ") + "\n"
+		}
+	}
+	if len(p.Unsatisfiable) > 0 {
+		s += fmt.Sprintf("%d unsatisfiable constraints:\n", len(p.Unsatisfiable))
+		for _, v := range p.Unsatisfiable {
+			versions := v.Versions
+			if len(versions) > 10 {
+				versions = append(versions[:10:10], "...")
+			}
+			s += fmt.Sprintf("|\t%s requires %s %s, available versions: %s\n", v.Dependent, v.Name, v.Constraint, strings.Join(versions, ", "))
+		}
+	}
+	s += "=== END ==="
+	return s
+}
+
+// Check print the problems and abort if any of them is fatal
+func (p Problems) Check() {
+	if p.Empty() {
+		return
+	}
+	fmt.Println(p.Inspect())
+	if p.Fatal() {
+		log.Fatal("The dependency tree can not be resolved, see the problems above.")
+	}
+}
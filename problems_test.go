@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+
+	simplejson "github.com/bitly/go-simplejson"
+)
+
+func Test_FindCycles(t *testing.T) {
+	// b reaches a through c, which is hoisted next to b and not its parent
+	nodes := Nodes{
+		"a:1.0.0": &Node{Name: "a", Version: "1.0.0", Dependencies: []string{"b:1.0.0", "c:1.0.0"}},
+		"b:1.0.0": &Node{Name: "b", Version: "1.0.0", Dependencies: []string{"c:1.0.0"}},
+		"c:1.0.0": &Node{Name: "c", Version: "1.0.0", Dependencies: []string{"a:1.0.0"}},
+		"d:1.0.0": &Node{Name: "d", Version: "1.0.0"},
+	}
+	p := Problems{}
+	p.FindCycles(nodes)
+	if len(p.Cycles) != 1 || !sameCycle(p.Cycles[0], []string{"a:1.0.0", "b:1.0.0", "c:1.0.0", "a:1.0.0"}) {
+		t.Errorf("Test Problems.FindCycles() failed, expected a -> b -> c -> a, got %v", p.Cycles)
+	}
+	delete(nodes, "c:1.0.0")
+	p = Problems{}
+	p.FindCycles(nodes)
+	if !p.Empty() {
+		t.Errorf("Test Problems.FindCycles() failed, found cycles in an acyclic graph: %v", p.Cycles)
+	}
+}
+
+func Test_AppendCycle(t *testing.T) {
+	p := Problems{}
+	p.AppendCycle([]string{"a", "b", "c", "a"})
+	p.AppendCycle([]string{"b", "c", "a", "b"})
+	p.AppendCycle([]string{"b", "a", "b"})
+	if len(p.Cycles) == 2 {
+		t.Log("Test Problems.AppendCycle() passed")
+	} else {
+		t.Errorf("Test Problems.AppendCycle() failed, expected 2 cycles, got %v", p.Cycles)
+	}
+}
+
+func Test_getDependenciesUnsatisfiable(t *testing.T) {
+	temp := NewTempData()
+	temp.ResponseCache["https://registry.npmjs.org/punycode"] = []byte(`{"_id": "punycode", "license": "MIT", "versions": {"2.1.1": {"license": "MIT", "dist": {}}}}`)
+	temp.ResponseCache["https://registry.npmjs.org/uri-js"] = []byte(`{"_id": "uri-js", "license": "BSD-2-Clause", "versions": {"4.4.1": {"license": "BSD-2-Clause", "dist": {}}}}`)
+	js, _ := simplejson.NewJson([]byte(`{"punycode": "^3.0.0", "uri-js": "^5.0.0"}`))
+	if deps := getDependencies(js, "ajv:6.12.6", temp); len(deps) != 0 {
+		t.Errorf("Test getDependencies() failed, expected no dependency, got %v", deps)
+	}
+	if len(temp.Problems.Unsatisfiable) != 2 || !temp.Problems.Fatal() {
+		t.Errorf("Test getDependencies() failed, expected both constraints collected, got %v", temp.Problems.Unsatisfiable)
+	}
+}
+
+func Test_ProblemsCheck(t *testing.T) {
+	if os.Getenv("NODE2RPM_TEST_CHECK") == "1" {
+		p := Problems{Unsatisfiable: []Unsatisfiable{{"ajv:6.12.6", "punycode", "^3.0.0", []string{"2.1.1"}}}}
+		p.Check()
+		return
+	}
+	// cycles alone are reported but not fatal
+	p := Problems{Cycles: [][]string{{"a:1.0.0", "b:1.0.0", "a:1.0.0"}}}
+	p.Check()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^Test_ProblemsCheck$")
+	cmd.Env = append(os.Environ(), "NODE2RPM_TEST_CHECK=1")
+	e := cmd.Run()
+	if ee, ok := e.(*exec.ExitError); !ok || ee.Success() {
+		t.Errorf("Test Problems.Check() failed, expected a fatal exit for unsatisfiable constraints, got %v", e)
+	}
+}
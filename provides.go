@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	semver "github.com/openSUSE-zh/node-semver"
+)
+
+// Provides the npm modules already packaged in the distribution: module name,
+// version and the rpm providing it, from "npm(name) = version" provides
+type Provides map[string]map[string]string
+
+// semverRe a version the semver library can parse, rpm versions may be anything
+var semverRe = regexp.MustCompile(`^\d+(\.\d+){0,2}(-[0-9A-Za-z.-]+)?$`)
+
+// Append records a provided module version, invalid semvers are ignored
+func (p Provides) Append(name, ver, rpm string) {
+	if !semverRe.MatchString(ver) {
+		return
+	}
+	if _, ok := p[name]; !ok {
+		p[name] = map[string]string{}
+	}
+	p[name][ver] = rpm
+}
+
+// Satisfying the newest provided version of a module satisfying the constraint
+func (p Provides) Satisfying(name, constraint string) (semver.Semver, bool) {
+	versions := semver.Collection{}
+	for v := range p[name] {
+		versions = append(versions, semver.NewSemver(v))
+	}
+	sort.Sort(sort.Reverse(versions))
+	v := getSemver(versions, constraint)
+	return v, len(v.String()) > 0
+}
+
+// ToExclusion rules excluding every provided module version, the modules
+// become "Requires:" of the package
+func (p Provides) ToExclusion() Exclusion {
+	names := []string{}
+	for k := range p {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	e := Exclusion{}
+	for _, name := range names {
+		versions := []string{}
+		rpms := map[string]struct{}{}
+		for v, rpm := range p[name] {
+			versions = append(versions, "= "+v)
+			rpms[rpm] = struct{}{}
+		}
+		sort.Strings(versions)
+		a := []string{}
+		for k := range rpms {
+			a = append(a, k)
+		}
+		sort.Strings(a)
+		r := newExclusionRule(name, strings.Join(versions, " || "), "packaged in the distribution as "+strings.Join(a, ", "), true)
+		r.Provided = true
+		e = append(e, r)
+	}
+	return e
+}
+
+// ReadProvides read the provides of a distribution repository, either a
+// repodata primary.xml, gzipped or not, or the output of "rpm -qa --provides"
+func ReadProvides(file string) Provides {
+	b := readMaybeGzip(file)
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("<")) {
+		p, e := parsePrimaryXML(bytes.NewReader(b))
+		if e != nil {
+			log.Fatalf("Can not parse repository metadata %s: %s", file, e)
+		}
+		return p
+	}
+	return parseRpmProvides(bytes.NewReader(b))
+}
+
+// readMaybeGzip read repository metadata, decompressed if gzipped
+func readMaybeGzip(file string) []byte {
+	b, e := ioutil.ReadFile(file)
+	if e != nil {
+		log.Fatalf("Can not read repository metadata %s: %s", file, e)
+	}
+	if !bytes.HasPrefix(b, []byte{0x1f, 0x8b}) {
+		return b
+	}
+	gz, e := gzip.NewReader(bytes.NewReader(b))
+	if e != nil {
+		log.Fatalf("Can not decompress repository metadata %s: %s", file, e)
+	}
+	b, e = ioutil.ReadAll(gz)
+	if e != nil {
+		log.Fatalf("Can not decompress repository metadata %s: %s", file, e)
+	}
+	return b
+}
+
+// parsePrimaryXML find the npm provides in a repodata primary.xml, eg:
+//
+//	<package type="rpm">
+//	  <name>nodejs-punycode</name>
+//	  <format>
+//	    <rpm:provides>
+//	      <rpm:entry name="npm(punycode)" flags="EQ" epoch="0" ver="2.1.1"/>
+func parsePrimaryXML(r io.Reader) (Provides, error) {
+	p := Provides{}
+	d := xml.NewDecoder(r)
+	name := ""
+	inName := false
+	inProvides := false
+	for {
+		t, e := d.Token()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return nil, e
+		}
+		switch v := t.(type) {
+		case xml.StartElement:
+			switch v.Name.Local {
+			case "package":
+				name = ""
+			case "name":
+				inName = true
+			case "provides":
+				inProvides = true
+			case "entry":
+				if !inProvides {
+					continue
+				}
+				attrs := map[string]string{}
+				for _, a := range v.Attr {
+					attrs[a.Name.Local] = a.Value
+				}
+				if module := npmProvide(attrs["name"]); len(module) > 0 && attrs["flags"] == "EQ" {
+					p.Append(module, attrs["ver"], name)
+				}
+			}
+		case xml.EndElement:
+			switch v.Name.Local {
+			case "name":
+				inName = false
+			case "provides":
+				inProvides = false
+			}
+		case xml.CharData:
+			if inName {
+				name += string(v)
+			}
+		}
+	}
+	return p, nil
+}
+
+// rpmProvideRe an npm provide of "rpm -qa --provides"
+var rpmProvideRe = regexp.MustCompile(`^(npm\(.+\))\s*=\s*(\S+)$`)
+
+// parseRpmProvides find the npm provides in the output of "rpm -qa --provides",
+// eg: "npm(punycode) = 2.1.1"
+func parseRpmProvides(r io.Reader) Provides {
+	p := Provides{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m := rpmProvideRe.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+		// rpm -qa --provides doesn't tell the providing rpm
+		p.Append(npmProvide(m[1]), m[2], "npm("+npmProvide(m[1])+")")
+	}
+	return p
+}
+
+// npmProvide the module name of an "npm(name)" provide, empty for other provides
+func npmProvide(s string) string {
+	if strings.HasPrefix(s, "npm(") && strings.HasSuffix(s, ")") {
+		return s[4 : len(s)-1]
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	semver "github.com/openSUSE-zh/node-semver"
+)
+
+const primaryXML = `<?xml version="1.0" encoding="UTF-8"?>
+<metadata xmlns="http://linux.duke.edu/metadata/common" xmlns:rpm="http://linux.duke.edu/metadata/rpm" packages="2">
+<package type="rpm">
+  <name>nodejs-punycode</name>
+  <version epoch="0" ver="2.1.1" rel="1.1"/>
+  <format>
+    <rpm:provides>
+      <rpm:entry name="nodejs-punycode" flags="EQ" epoch="0" ver="2.1.1" rel="1.1"/>
+      <rpm:entry name="npm(punycode)" flags="EQ" epoch="0" ver="2.1.1"/>
+    </rpm:provides>
+    <rpm:requires>
+      <rpm:entry name="npm(inherits)" flags="GE" epoch="0" ver="2.0.0"/>
+    </rpm:requires>
+  </format>
+</package>
+<package type="rpm">
+  <name>nodejs-inherits</name>
+  <format>
+    <rpm:provides>
+      <rpm:entry name="npm(inherits)" flags="EQ" epoch="0" ver="2.0.4"/>
+      <rpm:entry name="npm(broken)" flags="EQ" epoch="0" ver="git"/>
+    </rpm:provides>
+  </format>
+</package>
+</metadata>`
+
+func Test_parsePrimaryXML(t *testing.T) {
+	p, e := parsePrimaryXML(strings.NewReader(primaryXML))
+	if e != nil {
+		t.Fatalf("Test parsePrimaryXML() failed: %s", e)
+	}
+	if p["punycode"]["2.1.1"] != "nodejs-punycode" || p["inherits"]["2.0.4"] != "nodejs-inherits" {
+		t.Errorf("Test parsePrimaryXML() failed, expected punycode and inherits, got %v", p)
+	}
+	if _, ok := p["inherits"]["2.0.0"]; ok {
+		t.Errorf("Test parsePrimaryXML() failed, requires are not provides")
+	}
+	if _, ok := p["broken"]; ok {
+		t.Errorf("Test parsePrimaryXML() failed, invalid versions should be ignored")
+	}
+}
+
+func Test_parseRpmProvides(t *testing.T) {
+	p := parseRpmProvides(strings.NewReader("nodejs-punycode = 2.1.1-1.1\nnpm(punycode) = 2.1.1\nnpm(@types/node) = 14.14.31\n"))
+	if len(p) != 2 || len(p["@types/node"]["14.14.31"]) == 0 {
+		t.Errorf("Test parseRpmProvides() failed, got %v", p)
+	}
+}
+
+func Test_ProvidesToExclusion(t *testing.T) {
+	p := Provides{}
+	p.Append("punycode", "2.1.1", "nodejs-punycode")
+	p.Append("punycode", "1.4.1", "nodejs-punycode1")
+	if v, ok := p.Satisfying("punycode", "^1.3.0"); !ok || v.String() != "1.4.1" {
+		t.Errorf("Test Provides.Satisfying() failed, expected 1.4.1, got %s", v.String())
+	}
+	if _, ok := p.Satisfying("punycode", "^3.0.0"); ok {
+		t.Errorf("Test Provides.Satisfying() failed, nothing satisfies ^3.0.0")
+	}
+	e := p.ToExclusion()
+	if len(e) != 1 || !e[0].Requires || e[0].Reason != "packaged in the distribution as nodejs-punycode, nodejs-punycode1" {
+		t.Fatalf("Test Provides.ToExclusion() failed, got %s", e.Inspect())
+	}
+	if !e.Contains("punycode", semver.NewSemver("1.4.1")) || e.Contains("punycode", semver.NewSemver("2.0.0")) {
+		t.Errorf("Test Provides.ToExclusion() failed, expected only the provided versions excluded")
+	}
+
+	// rules of the distribution stay out of the output unless they matched
+	p.Append("left-pad", "1.3.0", "nodejs-left-pad")
+	e = append(Exclusion{newExclusionRule("@types/*", "", "", false)}, p.ToExclusion()...)
+	e.Contains("punycode", semver.NewSemver("2.1.1"))
+	if s := e.Inspect(); strings.Contains(s, "punycode") || !strings.Contains(s, "@types/*") || !strings.Contains(s, "and 2 modules packaged in the distribution") {
+		t.Errorf("Test Exclusion.Inspect() failed, got %s", s)
+	}
+	if s := e.Report(); strings.Contains(s, "left-pad") || !strings.Contains(s, "punycode:2.1.1") {
+		t.Errorf("Test Exclusion.Report() failed, got %s", s)
+	}
+	if u := e.Unmatched(); len(u) != 1 || u[0].Pattern != "@types/*" {
+		t.Errorf("Test Exclusion.Unmatched() failed, got %v", u)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// CommandRunner run a command in the directory, replaceable in tests
+type CommandRunner func(dir, name string, args ...string) error
+
+// execRunner run the command with os/exec, its output goes to ours
+func execRunner(dir, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// RemoveSources remove the stale sources from the working directory. In an
+// osc checkout "osc delete" drops them from the .osc tracking too, files osc
+// doesn't track yet are just removed. With dryRun nothing is touched.
+func RemoveSources(files []string, wd string, run CommandRunner, dryRun bool) {
+	_, e := os.Stat(filepath.Join(wd, ".osc"))
+	osc := e == nil
+	for _, f := range files {
+		if dryRun {
+			if osc {
+				log.Printf("Would run: osc delete %s", f)
+			} else {
+				log.Printf("Would remove %s", f)
+			}
+			continue
+		}
+		if osc {
+			e := run(wd, "osc", "delete", f)
+			if e == nil {
+				log.Printf("%s has been deleted with osc.", f)
+				continue
+			}
+			log.Printf("osc delete %s failed, removing the file only: %s", f, e)
+		}
+		if e := os.Remove(filepath.Join(wd, f)); e != nil && !os.IsNotExist(e) {
+			log.Printf("Can not remove %s: %s", f, e)
+			continue
+		}
+		log.Printf("%s has been removed.", f)
+	}
+}
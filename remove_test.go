@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_TarballsStale(t *testing.T) {
+	tb := Tarballs{}
+	tb.Append("https://registry.npmjs.org/punycode/-/punycode-2.1.1.tgz", "", "")
+	tb.Append("https://registry.npmjs.org/@types/node/-/node-14.14.31.tgz", "", "")
+	old := map[string]struct{}{"punycode-2.1.0.tgz": {}, "node-14.14.31.tgz": {}, "ajv-6.12.5.tgz": {}}
+	expected := []string{"ajv-6.12.5.tgz", "punycode-2.1.0.tgz"}
+	if stale := tb.Stale(old); !reflect.DeepEqual(stale, expected) {
+		t.Errorf("Test Tarballs.Stale() failed, expected %v, got %v", expected, stale)
+	}
+}
+
+func Test_RemoveSources(t *testing.T) {
+	wd, e := ioutil.TempDir("", "node2rpm")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(wd)
+	for _, f := range []string{"a.tgz", "b.tgz"} {
+		ioutil.WriteFile(filepath.Join(wd, f), []byte{}, 0644)
+	}
+
+	calls := [][]string{}
+	run := func(dir, name string, args ...string) error {
+		calls = append(calls, append([]string{name}, args...))
+		if args[1] == "b.tgz" {
+			return errors.New("b.tgz is not under version control")
+		}
+		return nil
+	}
+
+	RemoveSources([]string{"a.tgz"}, wd, run, true)
+	if _, e := os.Stat(filepath.Join(wd, "a.tgz")); e != nil || len(calls) > 0 {
+		t.Errorf("Test RemoveSources() failed, dry run should touch nothing")
+	}
+
+	RemoveSources([]string{"a.tgz"}, wd, run, false)
+	if _, e := os.Stat(filepath.Join(wd, "a.tgz")); !os.IsNotExist(e) || len(calls) > 0 {
+		t.Errorf("Test RemoveSources() failed, a.tgz should be removed without osc outside a checkout")
+	}
+
+	os.Mkdir(filepath.Join(wd, ".osc"), 0755)
+	RemoveSources([]string{"b.tgz"}, wd, run, false)
+	if !reflect.DeepEqual(calls, [][]string{{"osc", "delete", "b.tgz"}}) {
+		t.Errorf("Test RemoveSources() failed, expected osc delete b.tgz, got %v", calls)
+	}
+	if _, e := os.Stat(filepath.Join(wd, "b.tgz")); !os.IsNotExist(e) {
+		t.Errorf("Test RemoveSources() failed, b.tgz should be removed when osc fails")
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	semver "github.com/openSUSE-zh/node-semver"
+)
+
+// Requirement a module excluded from the bundle that the package depends on,
+// with the semver constraints of its dependents
+type Requirement struct {
+	Name   string
+	Ranges map[string]struct{}
+}
+
+// Requirements the excluded modules to become "Requires:" and "BuildRequires:"
+type Requirements map[string]*Requirement
+
+// Append records the constraint a dependent puts on an excluded module
+func (r Requirements) Append(name, constraint string) {
+	if _, ok := r[name]; !ok {
+		r[name] = &Requirement{name, map[string]struct{}{}}
+	}
+	r[name].Ranges[constraint] = struct{}{}
+}
+
+// Keys the sorted module names
+func (r Requirements) Keys() []string {
+	keys := []string{}
+	for k := range r {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// String RPM dependency lines of the tag, eg:
+//
+//	Requires:       npm(punycode) >= 2.1.0
+//	Requires:       npm(punycode) < 3.0.0
+func (r Requirements) String(tag string) string {
+	str := ""
+	for _, k := range r.Keys() {
+		for _, v := range r[k].Dependencies() {
+			str += tag + ":" + padding(tag) + v + "\n"
+		}
+	}
+	return str
+}
+
+// Dependencies the RPM dependencies satisfying every constraint on the module
+func (r Requirement) Dependencies() []string {
+	ranges := []string{}
+	for k := range r.Ranges {
+		ranges = append(ranges, k)
+	}
+	sort.Strings(ranges)
+
+	deps := []string{}
+	seen := map[string]struct{}{}
+	for _, v := range ranges {
+		for _, d := range rpmDependencies(r.Name, v) {
+			if _, ok := seen[d]; !ok {
+				seen[d] = struct{}{}
+				deps = append(deps, d)
+			}
+		}
+	}
+	if len(deps) == 0 {
+		deps = append(deps, "npm("+r.Name+")")
+	}
+	return deps
+}
+
+// rpmDependencies translate a node semver range to RPM dependencies.
+// A single comparator set becomes one dependency per comparator, "||"
+// alternatives a rich dependency, eg:
+//
+//	^1.2.0        => npm(x) >= 1.2.0, npm(x) < 2.0.0
+//	~1.2.0 || 3.x => ((npm(x) >= 1.2.0 with npm(x) < 1.3.0) or (npm(x) >= 3.0.0 with npm(x) < 4.0.0))
+//
+// Any version, "*" or "latest", becomes no dependency.
+func rpmDependencies(name, constraint string) []string {
+	sets := [][]string{}
+	for _, set := range semver.NewRange(strings.TrimSpace(constraint)) {
+		a := rpmComparators(name, set)
+		if len(a) == 0 {
+			// one alternative accepts any version, so does the whole range
+			return []string{}
+		}
+		sets = append(sets, a)
+	}
+	if len(sets) == 1 {
+		return sets[0]
+	}
+
+	alternatives := []string{}
+	for _, set := range sets {
+		if len(set) == 1 {
+			alternatives = append(alternatives, set[0])
+			continue
+		}
+		alternatives = append(alternatives, "("+strings.Join(set, " with ")+")")
+	}
+	return []string{"(" + strings.Join(alternatives, " or ") + ")"}
+}
+
+// rpmComparators the RPM comparisons of a comparator set, ">= 0.0.0" is dropped
+func rpmComparators(name string, set semver.ComparatorSet) []string {
+	a := []string{}
+	for _, c := range set {
+		if c.IsNil() || (c.Op == ">=" && c.Version.String() == "0.0.0") {
+			continue
+		}
+		op := c.Op
+		if op == "==" {
+			op = "="
+		}
+		a = append(a, "npm("+name+") "+op+" "+rpmVersion(c.Version))
+	}
+	return a
+}
+
+// rpmVersion a semver as RPM version, prereleases sort before the release
+// with "~" in RPM, build metadata is ignored by semver precedence
+func rpmVersion(v semver.Semver) string {
+	s := v.Major + "." + v.Minor + "." + v.Patch
+	if len(v.Prerelease) > 0 {
+		s += "~" + strings.Replace(v.Prerelease, "-", "_", -1)
+	}
+	return s
+}
+
+// padding align the value of a spec tag at column 17 like the template does
+func padding(tag string) string {
+	n := 15 - len(tag)
+	if n < 1 {
+		n = 1
+	}
+	return strings.Repeat(" ", n)
+}
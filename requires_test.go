@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_rpmDependencies(t *testing.T) {
+	tests := map[string][]string{
+		"^1.2.3":          {"npm(x) >= 1.2.3", "npm(x) < 2.0.0"},
+		"^0.2.3":          {"npm(x) >= 0.2.3", "npm(x) < 0.3.0"},
+		"~1.2.3":          {"npm(x) >= 1.2.3", "npm(x) < 1.3.0"},
+		"1.2.3":           {"npm(x) = 1.2.3"},
+		">=2.0.0":         {"npm(x) >= 2.0.0"},
+		"*":               {},
+		"latest":          {},
+		"^1.0.0-beta.1":   {"npm(x) >= 1.0.0~beta.1", "npm(x) < 2.0.0"},
+		"~1.2.0 || 3.x":   {"((npm(x) >= 1.2.0 with npm(x) < 1.3.0) or (npm(x) >= 3.0.0 with npm(x) < 4.0.0))"},
+		"1.0.0 || ^2.0.0": {"(npm(x) = 1.0.0 or (npm(x) >= 2.0.0 with npm(x) < 3.0.0))"},
+		"^1.0.0 || *":     {},
+	}
+	for k, expected := range tests {
+		if deps := rpmDependencies("x", k); !reflect.DeepEqual(deps, expected) {
+			t.Errorf("Test rpmDependencies() failed for %s, expected %v, got %v", k, expected, deps)
+		}
+	}
+}
+
+func Test_RequirementsString(t *testing.T) {
+	r := Requirements{}
+	r.Append("punycode", "^2.1.0")
+	r.Append("punycode", "^2.1.0")
+	r.Append("inherits", "*")
+	expected := "Requires:       npm(inherits)\nRequires:       npm(punycode) >= 2.1.0\nRequires:       npm(punycode) < 3.0.0\n"
+	if s := r.String("Requires"); s != expected {
+		t.Errorf("Test Requirements.String() failed, expected %q, got %q", expected, s)
+	}
+	if s := r.String("BuildRequires"); s[:len("BuildRequires:  npm(inherits)")] != "BuildRequires:  npm(inherits)" {
+		t.Errorf("Test Requirements.String() failed, BuildRequires misaligned: %q", s)
+	}
+}
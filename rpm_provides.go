@@ -0,0 +1,37 @@
+package main
+
+import semver "github.com/openSUSE-zh/node-semver"
+
+// modes of emitting the Provides of the package
+const (
+	// ProvidesAll root and bundled modules, without a dependency generator
+	ProvidesAll = "all"
+	// ProvidesBundled bundled modules only, the nodejs-packaging dependency
+	// generator provides npm(root) from the installed package.json
+	ProvidesBundled = "bundled"
+	// ProvidesNone no Provides at all, the generator does everything
+	ProvidesNone = "none"
+)
+
+// RPMProvides "Provides:" lines of the root module and every bundled module, eg:
+//
+//	Provides:       npm(har-validator) = 5.1.5
+//	Provides:       bundled(npm(ajv)) = 6.12.6
+func RPMProvides(root string, nodes Nodes, mode string) string {
+	str := ""
+	if mode == ProvidesNone {
+		return str
+	}
+	if mode == ProvidesAll {
+		name, ver := parseTreeKey(root)
+		str += "Provides:" + padding("Provides") + "npm(" + name + ") = " + rpmVersion(semver.NewSemver(ver)) + "\n"
+	}
+	for _, k := range nodes.Keys() {
+		if k == root {
+			continue
+		}
+		n := nodes[k]
+		str += "Provides:" + padding("Provides") + "bundled(npm(" + n.Name + ")) = " + rpmVersion(semver.NewSemver(n.Version)) + "\n"
+	}
+	return str
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func Test_RPMProvides(t *testing.T) {
+	nodes := Nodes{
+		"har-validator:5.1.5":    &Node{Name: "har-validator", Version: "5.1.5"},
+		"ajv:6.12.6":             &Node{Name: "ajv", Version: "6.12.6"},
+		"@types/node:1.0.0-rc.1": &Node{Name: "@types/node", Version: "1.0.0-rc.1"},
+	}
+	expected := "Provides:       npm(har-validator) = 5.1.5\nProvides:       bundled(npm(@types/node)) = 1.0.0~rc.1\nProvides:       bundled(npm(ajv)) = 6.12.6\n"
+	if s := RPMProvides("har-validator:5.1.5", nodes, ProvidesAll); s != expected {
+		t.Errorf("Test RPMProvides() failed, expected %q, got %q", expected, s)
+	}
+	if s := RPMProvides("har-validator:5.1.5", nodes, ProvidesBundled); s != expected[len("Provides:       npm(har-validator) = 5.1.5\n"):] {
+		t.Errorf("Test RPMProvides() failed, bundled mode should skip the root, got %q", s)
+	}
+	if s := RPMProvides("har-validator:5.1.5", nodes, ProvidesNone); len(s) > 0 {
+		t.Errorf("Test RPMProvides() failed, none mode should emit nothing, got %q", s)
+	}
+}
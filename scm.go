@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// output backends, how the sources of the package are fetched by OBS
+const (
+	// BackendDownloadURL a download_url service for every tarball
+	BackendDownloadURL = "download_url"
+	// BackendObsScm the upstream repository with obs_scm and the tarballs of
+	// package-lock.json in an obscpio with the node_modules service
+	BackendObsScm = "obs_scm"
+)
+
+// file names of the obs_scm backend
+const (
+	packageLock        = "package-lock.json"
+	nodeModulesCpio    = "node_modules.obscpio"
+	nodeModulesSpecInc = "node_modules.spec.inc"
+)
+
+// PackageLock a package-lock.json, lockfileVersion 3 only has "packages"
+type PackageLock struct {
+	Name            string                 `json:"name"`
+	Version         string                 `json:"version"`
+	LockfileVersion int                    `json:"lockfileVersion"`
+	Requires        bool                   `json:"requires"`
+	Packages        map[string]LockPackage `json:"packages"`
+}
+
+// LockPackage a module of package-lock.json, indexed by its install path
+type LockPackage struct {
+	Name         string            `json:"name,omitempty"`
+	Version      string            `json:"version"`
+	Resolved     string            `json:"resolved,omitempty"`
+	Integrity    string            `json:"integrity,omitempty"`
+	License      string            `json:"license,omitempty"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+}
+
+// NewPackageLock the package-lock.json of the tree, so the node_modules
+// service downloads and npm installs exactly the resolved modules
+func NewPackageLock(doc TreeDocument, nodes Nodes) PackageLock {
+	lock := PackageLock{doc.Root.Name, doc.Root.Version, 3, true, map[string]LockPackage{}}
+	doc.Root.Walk(func(tn TreeNode) {
+		p := LockPackage{Version: tn.Version, License: tn.License}
+		if n, ok := nodes[tn.Name+":"+tn.Version]; ok && len(n.Ranges) > 0 {
+			p.Dependencies = n.Ranges
+		}
+		if len(tn.Path) == 0 {
+			// the root is the checkout itself
+			p.Name = tn.Name
+		} else {
+			p.Resolved = tn.Resolved
+			p.Integrity = tn.Integrity
+		}
+		lock.Packages[tn.Path] = p
+	})
+	return lock
+}
+
+// Save write package-lock.json into the working directory
+func (lock PackageLock) Save(wd string) string {
+	b, e := json.MarshalIndent(lock, "", "  ")
+	if e != nil {
+		log.Fatalf("Can not generate %s: %s", packageLock, e)
+	}
+	if e := ioutil.WriteFile(filepath.Join(wd, packageLock), append(b, '\n'), 0644); e != nil {
+		log.Fatalf("Can not write %s: %s", packageLock, e)
+	}
+	return packageLock
+}
+
+// githubShorthandRe a "user/repo" repository, hosted on GitHub
+var githubShorthandRe = regexp.MustCompile(`^[\w.-]+/[\w.-]+$`)
+
+// gitURL the cloneable url of a package.json repository, eg:
+// "git+https://github.com/a/b.git", "github:a/b" and "a/b" are all
+// "https://github.com/a/b.git"
+func gitURL(repo string) string {
+	repo = strings.TrimSpace(repo)
+	if len(repo) == 0 {
+		return ""
+	}
+	repo = strings.TrimPrefix(repo, "git+")
+	if strings.HasPrefix(repo, "git://") {
+		repo = "https://" + strings.TrimPrefix(repo, "git://")
+	}
+	if strings.HasPrefix(repo, "ssh://git@") {
+		repo = "https://" + strings.TrimPrefix(repo, "ssh://git@")
+	}
+	hosts := map[string]string{"github": "github.com", "gitlab": "gitlab.com", "bitbucket": "bitbucket.org"}
+	if a := strings.SplitN(repo, ":", 2); len(a) == 2 {
+		if host, ok := hosts[a[0]]; ok {
+			repo = "https://" + host + "/" + a[1]
+		}
+	}
+	if githubShorthandRe.MatchString(repo) {
+		repo = "https://github.com/" + repo
+	}
+	if !strings.HasSuffix(repo, ".git") {
+		repo += ".git"
+	}
+	return repo
+}
+
+// scmFilename the name of the source archive of a module, scoped modules
+// have a slash
+func scmFilename(name string) string {
+	return strings.Replace(strings.TrimPrefix(name, "@"), "/", "-", -1)
+}
+
+// UpdateScm switch the file to the obs_scm backend. The npm download_url
+// services are dropped and the node_modules service is replaced. The obs_scm,
+// tar, recompress and set_version services are only added when missing, as
+// they may have been tuned by hand, but an existing obs_scm always checks out
+// the url and tag of ver, to match package-lock.json.
+func (sf *ServiceFile) UpdateScm(url, ver, filename string) error {
+	owned := sf.Tarballs()
+	items := []ServiceItem{}
+	names := map[string]struct{}{}
+	for _, v := range sf.Items {
+		if v.Service != nil {
+			if v.Service.Owned() || v.Service.verifies(owned) || v.Service.Name == "node_modules" {
+				continue
+			}
+			if v.Service.Name == "obs_scm" {
+				v.Service.SetParam("url", url)
+				v.Service.SetParam("revision", "v"+ver)
+			}
+			names[v.Service.Name] = struct{}{}
+		}
+		items = append(items, v)
+	}
+
+	services := []*Service{
+		{Name: "obs_scm", Mode: "manual", Params: []ServiceParam{
+			{"scm", "git"},
+			{"url", url},
+			{"revision", "v" + ver},
+			{"versionformat", "@PARENT_TAG@"},
+			{"versionrewrite-pattern", "v(.*)"},
+			{"filename", filename},
+		}},
+		{Name: "tar", Mode: "buildtime"},
+		{Name: "recompress", Mode: "buildtime", Params: []ServiceParam{
+			{"file", "*.tar"},
+			{"compression", "gz"},
+		}},
+		{Name: "set_version", Mode: "manual"},
+	}
+	for _, s := range services {
+		if _, ok := names[s.Name]; !ok {
+			items = append(items, ServiceItem{Service: s})
+		}
+	}
+	items = append(items, ServiceItem{Service: &Service{Name: "node_modules", Mode: "manual", Params: []ServiceParam{
+		{"cpio", nodeModulesCpio},
+		{"output", nodeModulesSpecInc},
+		{"source-offset", "10000"},
+	}}})
+	sf.Items = items
+	return sf.Validate()
+}
+
+// ToScmService switch _service to the obs_scm backend, the file names of the
+// replaced npm tarballs are returned. With dryRun _service is left as it is.
+func ToScmService(wd string, root *Node, dryRun bool) []string {
+	url := gitURL(root.Repository)
+	if len(url) == 0 {
+		log.Fatalf("%s has no repository in package.json, the %s backend needs one.", root.Name, BackendObsScm)
+	}
+	sf := ReadServiceFile(wd)
+	stale := Tarballs{}.Stale(sf.Tarballs())
+	if dryRun {
+		return stale
+	}
+	if e := sf.UpdateScm(url, root.Version, scmFilename(root.Name)); e != nil {
+		log.Fatalf("Can not update _service: %s", e)
+	}
+	sf.Save(wd)
+	return stale
+}
+
+// scmSources RPM Source lines of the obs_scm backend, the tarballs are
+// Sources of the included spec generated by the node_modules service
+func scmSources(name string) string {
+	return fmt.Sprintf("Source0:\t%s-%%{version}.tar.gz\nSource1:\t%s\n%%include %%{_sourcedir}/%s\n", scmFilename(name), packageLock, nodeModulesSpecInc)
+}
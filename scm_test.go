@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_gitURL(t *testing.T) {
+	tests := map[string]string{
+		"git+https://github.com/ahmadnassri/node-har-validator.git": "https://github.com/ahmadnassri/node-har-validator.git",
+		"git://github.com/bestiejs/punycode.js.git":                 "https://github.com/bestiejs/punycode.js.git",
+		"github:isaacs/inherits":                                    "https://github.com/isaacs/inherits.git",
+		"isaacs/inherits":                                           "https://github.com/isaacs/inherits.git",
+		"":                                                          "",
+	}
+	for k, v := range tests {
+		if u := gitURL(k); u != v {
+			t.Errorf("Test gitURL() failed for %q, expected %s, got %s", k, v, u)
+		}
+	}
+}
+
+func Test_NewPackageLock(t *testing.T) {
+	nodes := Nodes{
+		"har-validator:5.1.5": &Node{Name: "har-validator", Version: "5.1.5", Ranges: map[string]string{"ajv": "^6.12.3"}},
+		"ajv:6.12.6":          &Node{Name: "ajv", Version: "6.12.6", Ranges: map[string]string{}},
+	}
+	doc := TreeDocument{Root: TreeNode{Name: "har-validator", Version: "5.1.5", Dependencies: []TreeNode{
+		{Name: "ajv", Version: "6.12.6", Path: "node_modules/ajv", Resolved: "https://registry.npmjs.org/ajv/-/ajv-6.12.6.tgz", Integrity: "sha512-x"},
+	}}}
+	lock := NewPackageLock(doc, nodes)
+	if lock.LockfileVersion != 3 || len(lock.Packages) != 2 {
+		t.Fatalf("Test NewPackageLock() failed, got %v", lock)
+	}
+	if root := lock.Packages[""]; root.Name != "har-validator" || root.Dependencies["ajv"] != "^6.12.3" || len(root.Resolved) > 0 {
+		t.Errorf("Test NewPackageLock() failed, wrong root %v", root)
+	}
+	if p := lock.Packages["node_modules/ajv"]; p.Resolved != doc.Root.Dependencies[0].Resolved || p.Integrity != "sha512-x" || p.Dependencies != nil {
+		t.Errorf("Test NewPackageLock() failed, wrong ajv %v", p)
+	}
+}
+
+func Test_ServiceFileUpdateScm(t *testing.T) {
+	sf, _ := ParseServiceFile([]byte(handWrittenService))
+	if e := sf.UpdateScm("https://github.com/ahmadnassri/node-har-validator.git", "5.1.5", "har-validator"); e != nil {
+		t.Fatalf("Test ServiceFile.UpdateScm() failed: %s", e)
+	}
+	if e := sf.UpdateScm("https://github.com/ahmadnassri/node-har-validator.git", "5.1.5", "har-validator"); e != nil {
+		t.Fatalf("Test ServiceFile.UpdateScm() failed: %s", e)
+	}
+	names := []string{}
+	for _, s := range sf.Services() {
+		names = append(names, s.Name)
+	}
+	// the hand written obs_scm is kept, the npm download is dropped
+	expected := "obs_scm download_url set_version tar recompress node_modules"
+	if strings.Join(names, " ") != expected {
+		t.Errorf("Test ServiceFile.UpdateScm() failed, expected %s, got %s", expected, strings.Join(names, " "))
+	}
+	if len(sf.Tarballs()) > 0 {
+		t.Errorf("Test ServiceFile.UpdateScm() failed, npm tarballs should be dropped")
+	}
+
+	// a version bump checks out the new tag with the hand written obs_scm
+	if e := sf.UpdateScm("https://github.com/ahmadnassri/har-validator.git", "5.1.6", "har-validator"); e != nil {
+		t.Fatalf("Test ServiceFile.UpdateScm() failed: %s", e)
+	}
+	scm := sf.Services()[0]
+	if scm.Mode != "disabled" || scm.Param("revision") != "v5.1.6" || scm.Param("url") != "https://github.com/ahmadnassri/har-validator.git" || len(scm.Params) != 2 {
+		t.Errorf("Test ServiceFile.UpdateScm() failed, expected the obs_scm at v5.1.6 with its mode kept, got %v", scm)
+	}
+}
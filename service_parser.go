@@ -1,27 +1,304 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
 )
 
-func parseService(wd string) map[string]struct{} {
-	re := regexp.MustCompile(`<param name="path">(.*?)<`)
-	tgz := make(map[string]struct{})
-	service := filepath.Join(wd, "_service")
-	if _, err := os.Stat(service); !os.IsNotExist(err) {
-		f, _ := os.Open(service)
-		scanner := bufio.NewScanner(f)
-		for scanner.Scan() {
-			t := scanner.Text()
-			if re.MatchString(t) {
-				t = re.FindStringSubmatch(t)[1]
-				tgz[filepath.Base(t)] = struct{}{}
+// npmTarballRe the path of a tarball in the npm registry, download_url
+// services with such path are owned by node2rpm
+var npmTarballRe = regexp.MustCompile(`^/(@[^/]+/)?[^/]+/-/[^/]+\.tgz$`)
+
+// ServiceFile an OBS _service file. Services and comments are kept in order,
+// so services added by hand survive rewriting.
+type ServiceFile struct {
+	Items []ServiceItem
+}
+
+// ServiceItem a service or a comment between services
+type ServiceItem struct {
+	Service *Service
+	Comment string
+}
+
+// Service a service of the _service file, eg: download_url, obs_scm, set_version
+type Service struct {
+	XMLName xml.Name       `xml:"service"`
+	Name    string         `xml:"name,attr"`
+	Mode    string         `xml:"mode,attr,omitempty"`
+	Comment string         `xml:",comment"`
+	Params  []ServiceParam `xml:"param"`
+}
+
+// ServiceParam a param of a service
+type ServiceParam struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Param the value of the named param, empty if the service doesn't have it
+func (s Service) Param(name string) string {
+	for _, p := range s.Params {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// SetParam set the value of the named param, it's added if missing
+func (s *Service) SetParam(name, value string) {
+	for i, p := range s.Params {
+		if p.Name == name {
+			s.Params[i].Value = value
+			return
+		}
+	}
+	s.Params = append(s.Params, ServiceParam{name, value})
+}
+
+// Owned if the service downloads an npm tarball, those are managed by node2rpm
+func (s Service) Owned() bool {
+	return s.Name == "download_url" && npmTarballRe.MatchString(s.Param("path"))
+}
+
+// newDownloadService a download_url service of the uri
+func newDownloadService(uri string) (*Service, error) {
+	u, e := url.Parse(uri)
+	if e != nil {
+		return nil, e
+	}
+	return &Service{Name: "download_url", Mode: "localonly", Params: []ServiceParam{
+		{"protocol", u.Scheme},
+		{"host", u.Host},
+		{"path", u.Path},
+	}}, nil
+}
+
+// ReadServiceFile read _service in the working directory, an empty ServiceFile
+// if there's none
+func ReadServiceFile(wd string) *ServiceFile {
+	b, e := ioutil.ReadFile(filepath.Join(wd, "_service"))
+	if os.IsNotExist(e) {
+		return &ServiceFile{}
+	}
+	if e != nil {
+		log.Fatalf("Can not read _service: %s", e)
+	}
+	sf, e := ParseServiceFile(b)
+	if e != nil {
+		log.Fatalf("Can not parse _service: %s", e)
+	}
+	return sf
+}
+
+// ParseServiceFile parse the content of a _service file
+func ParseServiceFile(b []byte) (*ServiceFile, error) {
+	sf := &ServiceFile{}
+	d := xml.NewDecoder(bytes.NewReader(b))
+	root := false
+	for {
+		t, e := d.Token()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return nil, e
+		}
+		switch v := t.(type) {
+		case xml.StartElement:
+			if !root {
+				if v.Name.Local != "services" {
+					return nil, fmt.Errorf("root element is <%s>, not <services>", v.Name.Local)
+				}
+				root = true
+				continue
+			}
+			if v.Name.Local != "service" {
+				return nil, fmt.Errorf("unexpected element <%s> in <services>", v.Name.Local)
+			}
+			s := &Service{}
+			if e := d.DecodeElement(s, &v); e != nil {
+				return nil, e
 			}
+			sf.Items = append(sf.Items, ServiceItem{Service: s})
+		case xml.Comment:
+			sf.Items = append(sf.Items, ServiceItem{Comment: string(v)})
 		}
-		f.Close()
 	}
-	return tgz
+	if !root {
+		return nil, fmt.Errorf("no <services> element")
+	}
+	return sf, sf.Validate()
+}
+
+// Services the services of the file, without comments
+func (sf ServiceFile) Services() []*Service {
+	a := []*Service{}
+	for _, v := range sf.Items {
+		if v.Service != nil {
+			a = append(a, v.Service)
+		}
+	}
+	return a
+}
+
+// Tarballs file names of the npm tarballs downloaded by the owned services
+func (sf ServiceFile) Tarballs() map[string]struct{} {
+	m := map[string]struct{}{}
+	for _, s := range sf.Services() {
+		if s.Owned() {
+			m[filepath.Base(s.Param("path"))] = struct{}{}
+		}
+	}
+	return m
+}
+
+// Update replace the owned services with download_url services of the
+// tarballs, where the first owned service was. With verify a verify_file
+// service checking the strongest hash follows every download. Foreign
+// services and comments are kept.
+func (sf *ServiceFile) Update(tb Tarballs, verify bool) error {
+	owned := sf.Tarballs()
+	services := []ServiceItem{}
+	for _, uri := range tb.Keys() {
+		s, e := newDownloadService(uri)
+		if e != nil {
+			return e
+		}
+		services = append(services, ServiceItem{Service: s})
+		owned[filepath.Base(uri)] = struct{}{}
+		if verify {
+			if v := newVerifyService(filepath.Base(uri), tb[uri]); v != nil {
+				services = append(services, ServiceItem{Service: v})
+			}
+		}
+	}
+
+	items := []ServiceItem{}
+	inserted := false
+	for _, v := range sf.Items {
+		if v.Service != nil && (v.Service.Owned() || v.Service.verifies(owned)) {
+			if !inserted {
+				items = append(items, services...)
+				inserted = true
+			}
+			continue
+		}
+		items = append(items, v)
+	}
+	if !inserted {
+		items = append(items, services...)
+	}
+	sf.Items = items
+	return sf.Validate()
+}
+
+// newVerifyService a verify_file service of the sha256 of the tarball, the
+// only verifier obs-service-verify_file supports. nil if it wasn't computed,
+// see Tarballs.HashSha256
+func newVerifyService(file string, t Tarball) *Service {
+	if len(t.Sha256) == 0 {
+		return nil
+	}
+	return &Service{Name: "verify_file", Params: []ServiceParam{
+		{"file", file},
+		{"verifier", "sha256"},
+		{"checksum", t.Sha256},
+	}}
+}
+
+// verifies if the service is a verify_file of one of the files
+func (s Service) verifies(files map[string]struct{}) bool {
+	if s.Name != "verify_file" {
+		return false
+	}
+	_, ok := files[s.Param("file")]
+	return ok
+}
+
+// Validate every service has a name, download_url services a protocol, host
+// and path, and no file is downloaded twice
+func (sf ServiceFile) Validate() error {
+	paths := map[string]struct{}{}
+	for i, s := range sf.Services() {
+		if len(s.Name) == 0 {
+			return fmt.Errorf("service %d has no name", i+1)
+		}
+		if s.Name == "verify_file" {
+			for _, p := range []string{"file", "checksum"} {
+				if len(s.Param(p)) == 0 {
+					return fmt.Errorf("verify_file service %d has no %s", i+1, p)
+				}
+			}
+			continue
+		}
+		if s.Name != "download_url" {
+			continue
+		}
+		for _, p := range []string{"host", "path"} {
+			if len(s.Param(p)) == 0 {
+				return fmt.Errorf("download_url service %d has no %s", i+1, p)
+			}
+		}
+		f := filepath.Base(s.Param("path"))
+		if _, ok := paths[f]; ok {
+			return fmt.Errorf("%s is downloaded twice", f)
+		}
+		paths[f] = struct{}{}
+	}
+	return nil
+}
+
+// Bytes the XML of the file, indented with tabs
+func (sf ServiceFile) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "\t")
+	root := xml.StartElement{Name: xml.Name{Local: "services"}}
+	if e := enc.EncodeToken(root); e != nil {
+		return nil, e
+	}
+	for _, v := range sf.Items {
+		if v.Service == nil {
+			if e := enc.EncodeToken(xml.Comment(v.Comment)); e != nil {
+				return nil, e
+			}
+			continue
+		}
+		if e := enc.Encode(v.Service); e != nil {
+			return nil, e
+		}
+	}
+	if e := enc.EncodeToken(root.End()); e != nil {
+		return nil, e
+	}
+	if e := enc.Flush(); e != nil {
+		return nil, e
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// Save write the file to _service in the working directory, the written
+// file is parsed again to make sure OBS can read it
+func (sf ServiceFile) Save(wd string) {
+	b, e := sf.Bytes()
+	if e != nil {
+		log.Fatalf("Can not generate _service: %s", e)
+	}
+	if _, e := ParseServiceFile(b); e != nil {
+		log.Fatalf("Generated an invalid _service: %s", e)
+	}
+	if e := ioutil.WriteFile(filepath.Join(wd, "_service"), b, 0644); e != nil {
+		log.Fatalf("Can not write _service: %s", e)
+	}
 }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const handWrittenService = `<services>
+	<!-- keep the upstream git checkout -->
+	<service name="obs_scm" mode="disabled">
+		<param name="url">https://github.com/ahmadnassri/node-har-validator.git</param>
+	</service>
+	<service name="download_url" mode="localonly">
+		<param name="protocol">https</param>
+		<param name="host">registry.npmjs.org</param>
+		<param name="path">/punycode/-/punycode-2.1.0.tgz</param>
+	</service>
+	<service name="download_url" mode="localonly">
+		<param name="protocol">https</param>
+		<param name="host">example.com</param>
+		<param name="path">/patches/fix.patch</param>
+	</service>
+	<service name="set_version" mode="manual"/>
+</services>
+`
+
+func Test_ParseServiceFile(t *testing.T) {
+	sf, e := ParseServiceFile([]byte(handWrittenService))
+	if e != nil {
+		t.Fatalf("Test ParseServiceFile() failed: %s", e)
+	}
+	if len(sf.Items) != 5 || len(sf.Services()) != 4 {
+		t.Errorf("Test ParseServiceFile() failed, expected 4 services and a comment, got %v", sf.Items)
+	}
+	m := sf.Tarballs()
+	if _, ok := m["punycode-2.1.0.tgz"]; !ok || len(m) != 1 {
+		t.Errorf("Test ServiceFile.Tarballs() failed, expected only punycode-2.1.0.tgz, got %v", m)
+	}
+	if _, e := ParseServiceFile([]byte("<services><service/></services>")); e == nil {
+		t.Errorf("Test ParseServiceFile() failed, a service without name should be invalid")
+	}
+	if _, e := ParseServiceFile([]byte("<service name=\"x\"/>")); e == nil {
+		t.Errorf("Test ParseServiceFile() failed, the root must be <services>")
+	}
+}
+
+func Test_ServiceFileUpdate(t *testing.T) {
+	sf, _ := ParseServiceFile([]byte(handWrittenService))
+	tb := Tarballs{}
+	tb.Append("https://registry.npmjs.org/@types/node/-/node-14.14.31.tgz", "", "")
+	tb.Append("https://registry.npmjs.org/punycode/-/punycode-2.1.1.tgz", "", "")
+	e := sf.Update(tb, false)
+	if e != nil {
+		t.Fatalf("Test ServiceFile.Update() failed: %s", e)
+	}
+	b, e := sf.Bytes()
+	if e != nil {
+		t.Fatalf("Test ServiceFile.Bytes() failed: %s", e)
+	}
+	s := string(b)
+	for _, v := range []string{"<!-- keep the upstream git checkout -->", "obs_scm", "/patches/fix.patch", "set_version", "/@types/node/-/node-14.14.31.tgz", "/punycode/-/punycode-2.1.1.tgz"} {
+		if !strings.Contains(s, v) {
+			t.Errorf("Test ServiceFile.Update() failed, %s is lost:\n%s", v, s)
+		}
+	}
+	if strings.Contains(s, "punycode-2.1.0.tgz") {
+		t.Errorf("Test ServiceFile.Update() failed, the old punycode tarball should be replaced:\n%s", s)
+	}
+	if strings.Index(s, "node-14.14.31.tgz") > strings.Index(s, "fix.patch") {
+		t.Errorf("Test ServiceFile.Update() failed, the tarballs should replace the old one in place:\n%s", s)
+	}
+}
+
+func Test_ServiceFileUpdateVerify(t *testing.T) {
+	sf, _ := ParseServiceFile([]byte(handWrittenService))
+	tb := Tarballs{}
+	uri := "https://registry.npmjs.org/punycode/-/punycode-2.1.1.tgz"
+	tb.Append(uri, "", "86f7e437faa5a7fce15d1ddcb9eaeaea377667b8")
+	// the tarball is "a" matching its shasum, verify_file only knows sha256
+	tb.HashSha256(ResponseCache{uri: []byte("a")})
+	if e := sf.Update(tb, true); e != nil {
+		t.Fatalf("Test ServiceFile.Update() failed: %s", e)
+	}
+	if e := sf.Update(tb, true); e != nil {
+		t.Fatalf("Test ServiceFile.Update() failed: %s", e)
+	}
+	verify := 0
+	for _, s := range sf.Services() {
+		if s.Name == "verify_file" {
+			verify++
+			if s.Param("file") != "punycode-2.1.1.tgz" || s.Param("verifier") != "sha256" || s.Param("checksum") != "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb" {
+				t.Errorf("Test ServiceFile.Update() failed, wrong verify_file %v", s.Params)
+			}
+		}
+	}
+	if verify != 1 {
+		t.Errorf("Test ServiceFile.Update() failed, expected one verify_file service after updating twice, got %d", verify)
+	}
+}
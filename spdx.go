@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SPDX an SPDX 2.3 document
+type SPDX struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      SPDXCreationInfo   `json:"creationInfo"`
+	Packages          []SPDXPackage      `json:"packages"`
+	Relationships     []SPDXRelationship `json:"relationships"`
+}
+
+// SPDXCreationInfo who and when the document was created
+type SPDXCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+// SPDXPackage a bundled module
+type SPDXPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	Checksums        []SPDXChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []SPDXExternalRef `json:"externalRefs,omitempty"`
+}
+
+// SPDXChecksum a checksum of the module's tarball
+type SPDXChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+// SPDXExternalRef an external reference, the purl here
+type SPDXExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+// SPDXRelationship a relationship between two SPDX elements
+type SPDXRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxHashAlgs maps integrity algorithms to SPDX checksum algorithms
+var spdxHashAlgs = map[string]string{
+	"sha1":   "SHA1",
+	"sha256": "SHA256",
+	"sha384": "SHA384",
+	"sha512": "SHA512",
+}
+
+// NewSPDX build an SPDX document from the resolved nodes, root is the
+// "name:version" key of the module to package
+func NewSPDX(root string, nodes Nodes) SPDX {
+	doc := SPDX{
+		SPDXVersion:   "SPDX-2.3",
+		DataLicense:   "CC0-1.0",
+		SPDXID:        "SPDXRef-DOCUMENT",
+		Name:          strings.Replace(root, ":", "-", -1),
+		Packages:      []SPDXPackage{},
+		Relationships: []SPDXRelationship{},
+	}
+	doc.DocumentNamespace = "https://build.opensuse.org/spdxdocs/" + doc.Name + "-" + nodes.UUID()
+	doc.CreationInfo = SPDXCreationInfo{buildTime().Format(time.RFC3339), []string{"Tool: node2rpm"}}
+
+	for _, k := range nodes.Keys() {
+		n := nodes[k]
+		doc.Packages = append(doc.Packages, n.toSPDX())
+		if k == root {
+			doc.Relationships = append(doc.Relationships, SPDXRelationship{doc.SPDXID, "DESCRIBES", n.SPDXID()})
+		}
+		for _, d := range n.Dependencies {
+			if dn, ok := nodes[d]; ok {
+				doc.Relationships = append(doc.Relationships, SPDXRelationship{n.SPDXID(), "DEPENDS_ON", dn.SPDXID()})
+			}
+		}
+	}
+
+	return doc
+}
+
+// spdxIDRe characters not allowed in SPDX identifiers
+var spdxIDRe = regexp.MustCompile(`[^A-Za-z0-9.\-]+`)
+
+// SPDXID the SPDX identifier of the node, only letters, numbers, "." and "-" are allowed
+func (n Node) SPDXID() string {
+	return "SPDXRef-Package-npm-" + strings.Trim(spdxIDRe.ReplaceAllString(n.Name, "-"), "-") + "-" + spdxIDRe.ReplaceAllString(n.Version, "-")
+}
+
+func (n Node) toSPDX() SPDXPackage {
+	p := SPDXPackage{
+		SPDXID:           n.SPDXID(),
+		Name:             n.Name,
+		VersionInfo:      n.Version,
+		DownloadLocation: "NOASSERTION",
+		LicenseConcluded: "NOASSERTION",
+		LicenseDeclared:  "NOASSERTION",
+		CopyrightText:    "NOASSERTION",
+		ExternalRefs:     []SPDXExternalRef{{"PACKAGE-MANAGER", "purl", n.Purl()}},
+	}
+	if len(n.Tarball) > 0 {
+		p.DownloadLocation = n.Tarball
+	}
+	// licenses not known to SPDX would make the document invalid
+	if len(n.License) > 0 && len(n.UnknownLicenses) == 0 {
+		p.LicenseDeclared = n.License
+	}
+	checksums := n.Checksums()
+	for _, alg := range []string{"sha1", "sha256", "sha384", "sha512"} {
+		if v, ok := checksums[alg]; ok {
+			p.Checksums = append(p.Checksums, SPDXChecksum{spdxHashAlgs[alg], v})
+		}
+	}
+	return p
+}
+
+// TagValue convert the document to the SPDX tag-value format
+func (doc SPDX) TagValue() string {
+	s := "SPDXVersion: " + doc.SPDXVersion + "\n"
+	s += "DataLicense: " + doc.DataLicense + "\n"
+	s += "SPDXID: " + doc.SPDXID + "\n"
+	s += "DocumentName: " + doc.Name + "\n"
+	s += "DocumentNamespace: " + doc.DocumentNamespace + "\n"
+	for _, v := range doc.CreationInfo.Creators {
+		s += "Creator: " + v + "\n"
+	}
+	s += "Created: " + doc.CreationInfo.Created + "\n"
+
+	for _, p := range doc.Packages {
+		s += "\n"
+		s += "PackageName: " + p.Name + "\n"
+		s += "SPDXID: " + p.SPDXID + "\n"
+		s += "PackageVersion: " + p.VersionInfo + "\n"
+		s += "PackageDownloadLocation: " + p.DownloadLocation + "\n"
+		s += "FilesAnalyzed: false\n"
+		for _, c := range p.Checksums {
+			s += "PackageChecksum: " + c.Algorithm + ": " + c.ChecksumValue + "\n"
+		}
+		s += "PackageLicenseConcluded: " + p.LicenseConcluded + "\n"
+		s += "PackageLicenseDeclared: " + p.LicenseDeclared + "\n"
+		s += "PackageCopyrightText: " + p.CopyrightText + "\n"
+		for _, r := range p.ExternalRefs {
+			s += "ExternalRef: " + r.ReferenceCategory + " " + r.ReferenceType + " " + r.ReferenceLocator + "\n"
+		}
+	}
+
+	s += "\n"
+	for _, r := range doc.Relationships {
+		s += "Relationship: " + r.SPDXElementID + " " + r.RelationshipType + " " + r.RelatedSPDXElement + "\n"
+	}
+	return s
+}
+
+// Save write the document to "<name>.spdx" and "<name>.spdx.json" in the
+// osc working directory
+func (doc SPDX) Save(wd, name string) []string {
+	b, e := json.MarshalIndent(doc, "", "\t")
+	if e != nil {
+		log.Fatalf("Can not convert the SPDX document to json: %s", e)
+	}
+	files := []string{name + ".spdx", name + ".spdx.json"}
+	for i, v := range [][]byte{[]byte(doc.TagValue()), b} {
+		if e = ioutil.WriteFile(filepath.Join(wd, files[i]), v, 0644); e != nil {
+			log.Fatalf("Can not write SPDX document %s: %s", files[i], e)
+		}
+	}
+	return files
+}
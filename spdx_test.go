@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_NewSPDX(t *testing.T) {
+	nodes := Nodes{}
+	nodes["har-validator:5.1.3"] = &Node{Name: "har-validator", Version: "5.1.3", License: "MIT",
+		Tarball: "https://registry.npmjs.org/har-validator/-/har-validator-5.1.3.tgz", Dependencies: []string{"@types/node:14.14.31"}}
+	nodes["@types/node:14.14.31"] = &Node{Name: "@types/node", Version: "14.14.31"}
+	t.Setenv("SOURCE_DATE_EPOCH", "499162500")
+	doc := NewSPDX("har-validator:5.1.3", nodes)
+
+	answers := []string{
+		"Relationship: SPDXRef-DOCUMENT DESCRIBES SPDXRef-Package-npm-har-validator-5.1.3\n",
+		"Relationship: SPDXRef-Package-npm-har-validator-5.1.3 DEPENDS_ON SPDXRef-Package-npm-types-node-14.14.31\n",
+		"PackageDownloadLocation: https://registry.npmjs.org/har-validator/-/har-validator-5.1.3.tgz\n",
+		"PackageLicenseDeclared: NOASSERTION\n",
+		"Created: 1985-10-26T08:15:00Z\n",
+	}
+	s := doc.TagValue()
+	for _, v := range answers {
+		if strings.Contains(s, v) {
+			t.Logf("Test SPDX.TagValue() succeed, found %s", v)
+		} else {
+			t.Errorf("Test SPDX.TagValue() failed, %s not found in\n%s", v, s)
+		}
+	}
+}
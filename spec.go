@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -14,6 +15,35 @@ type Specfile struct {
 	Templated        bool
 	Raw              []byte
 	WorkingDirectory string
+	Artifacts        []Artifact
+	// Licenses license files of the bundled modules, relative to the module's
+	// install directory
+	Licenses []string
+	// ProvidesMode which Provides to emit, see ProvidesAll
+	ProvidesMode string
+	// Vendored the node_modules archive and its manifest replacing the
+	// tarballs of the bundled modules, empty if not vendored
+	Vendored []string
+	// Backend how OBS fetches the sources, see BackendDownloadURL
+	Backend string
+	// Native the native addons of the bundle, the package is not noarch
+	Native []NativeAddon
+	// Removals %prep lines removing prebuilt binaries
+	Removals []string
+	// Bins, Mans the executables and man pages of the module
+	Bins []Executable
+	Mans []ManPage
+	// Checksums the sha512sum file of the tarballs, a Source checked in
+	// %prep, empty if there is none
+	Checksums string
+}
+
+// Artifact a file generated by node2rpm next to the spec, shipped as
+// a Source and installed into Dir. License artifacts are marked %license.
+type Artifact struct {
+	File    string
+	Dir     string
+	License bool
 }
 
 // NewSpecfile initialize a new Specfile structure
@@ -31,22 +61,163 @@ func NewSpecfile(name, wd, specTemplate string) Specfile {
 		log.Printf("Can not find or read specfile %s", filepath.Join(wd, name+".spec"))
 	}
 
-	return Specfile{name, templated, raw, wd}
+	return Specfile{
+		Name:             name,
+		Templated:        templated,
+		Raw:              raw,
+		WorkingDirectory: wd,
+		Artifacts:        []Artifact{},
+		Licenses:         []string{},
+		ProvidesMode:     ProvidesAll,
+		Vendored:         []string{},
+		Backend:          BackendDownloadURL,
+		Native:           []NativeAddon{},
+		Removals:         []string{},
+		Bins:             []Executable{},
+		Mans:             []ManPage{},
+	}
 }
 
 func (s *Specfile) Fill(pkg, ver string, bundle bool, temp TempData) {
 	raw := string(s.Raw)
 	if s.Templated {
+		// the Sources before the artifacts
+		sources, idx := temp.Tarballs.String(), len(temp.Tarballs)
+		buildreq := temp.Requirements.String("BuildRequires")
+		if s.Backend == BackendObsScm {
+			sources, idx = scmSources(pkg), 2
+			buildreq = "BuildRequires:" + padding("BuildRequires") + "local-npm-registry\n" + buildreq
+		}
+		if len(s.Vendored) > 0 {
+			buildreq = "BuildRequires:" + padding("BuildRequires") + "zstd\n" + buildreq
+		}
 		raw = strings.Replace(raw, "<PACKAGE>", pkg, -1)
 		raw = strings.Replace(raw, "<VERSION>", ver, -1)
-		raw = strings.Replace(raw, "<SOURCE>", temp.Tarballs.String(), 1)
+		raw = strings.Replace(raw, "<SOURCE>", sources+s.sources(idx), 1)
+		raw = strings.Replace(raw, "<PREP>", s.prep(pkg, idx), 1)
+		raw = strings.Replace(raw, "<BUILD>", s.build(), 1)
+		raw = strings.Replace(raw, "<BUILDREQ>", strings.TrimSuffix(buildreq, "\n"), 1)
+		raw = strings.Replace(raw, "<ARCH>", archLines(s.Native), 1)
+		raw = strings.Replace(raw, "<REQUIRES>", strings.TrimSuffix(temp.Requirements.String("Requires"), "\n"), 1)
+		raw = strings.Replace(raw, "<PROVIDES>", strings.TrimSuffix(RPMProvides(pkg+":"+ver, temp.Nodes, s.ProvidesMode), "\n"), 1)
 		raw = strings.Replace(raw, "<LICENSE>", temp.Licenses.String(), 1)
+		raw = strings.Replace(raw, "<INSTALL>", s.install(idx+len(s.Vendored)), 1)
+		raw = strings.Replace(raw, "<FILES>", s.files(), 1)
 	} else {
 
 	}
 	s.Raw = []byte(raw)
 }
 
+// AddArtifact register a generated file to be installed into dir
+func (s *Specfile) AddArtifact(file, dir string) {
+	s.Artifacts = append(s.Artifacts, Artifact{file, dir, false})
+}
+
+// licenseDir where the license files of the bundle are installed. The copies
+// in %{nodejs_sitelib} are in files.lst already, listing them again as
+// %license makes rpm warn "File listed twice".
+const licenseDir = "%{_licensedir}/%{name}"
+
+// AddLicenseArtifact register a generated license file
+func (s *Specfile) AddLicenseArtifact(file string) {
+	s.Artifacts = append(s.Artifacts, Artifact{file, licenseDir, true})
+}
+
+// sources RPM Source lines of the vendored node_modules, the artifacts and
+// the checksum file, numbered after the tarballs
+func (s Specfile) sources(idx int) string {
+	str := ""
+	for _, v := range s.Vendored {
+		str += "Source" + strconv.Itoa(idx) + ":\t" + v + "\n"
+		idx++
+	}
+	for i, a := range s.Artifacts {
+		str += "Source" + strconv.Itoa(idx+i) + ":\t" + a.File + "\n"
+	}
+	if len(s.Checksums) > 0 {
+		str += "Source" + strconv.Itoa(idx+len(s.Artifacts)) + ":\t" + s.Checksums + "\n"
+	}
+	return str
+}
+
+// prep %prep lines. The npm tarballs are checked against the checksum file
+// and unpacked by %nodejs_prep, with the vendored node_modules and without
+// the prebuilt binaries. With the obs_scm backend the git checkout is set up
+// with our package-lock.json, its modules are installed in %build.
+func (s Specfile) prep(pkg string, idx int) string {
+	if s.Backend == BackendObsScm {
+		return "%setup -q -n " + scmFilename(pkg) + "-%{version}\ncp %{SOURCE1} " + packageLock
+	}
+	str := ""
+	if len(s.Checksums) > 0 {
+		n := idx + len(s.Vendored) + len(s.Artifacts)
+		str += "(cd %{_sourcedir} && sha512sum --quiet -c %{SOURCE" + strconv.Itoa(n) + "})\n"
+	}
+	str += "%nodejs_prep\n"
+	if len(s.Vendored) > 0 {
+		str += "tar --zstd -xf %{SOURCE" + strconv.Itoa(idx) + "}\n"
+	}
+	str += s.removals()
+	return strings.TrimSuffix(str, "\n")
+}
+
+// build %build lines. With the obs_scm backend the modules of
+// package-lock.json are installed from the tarballs of the obscpio, which
+// the node_modules service unpacks into %{_sourcedir}.
+func (s Specfile) build() string {
+	if s.Backend == BackendObsScm {
+		return strings.TrimSuffix("local-npm-registry %{_sourcedir} install --omit=dev\n"+s.removals(), "\n")
+	}
+	return "%nodejs_build"
+}
+
+// removals the lines removing the prebuilt binaries
+func (s Specfile) removals() string {
+	if len(s.Removals) == 0 {
+		return ""
+	}
+	return "# prebuilt binaries, openSUSE builds from source\n" + strings.Join(s.Removals, "\n") + "\n"
+}
+
+// install %install lines of the artifacts, the executables, man pages and
+// copies of the license files of the bundled modules
+func (s Specfile) install(idx int) string {
+	str := binLines(s.Bins, s.Mans)
+	for i, a := range s.Artifacts {
+		str += "install -D -m 0644 %{SOURCE" + strconv.Itoa(idx+i) + "} %{buildroot}" + a.Dir + "/" + a.File + "\n"
+	}
+	for _, v := range s.Licenses {
+		str += "install -D -m 0644 %{buildroot}%{nodejs_sitelib}/%{mod_name}/" + v + " %{buildroot}" + licenseDir + "/" + v + "\n"
+	}
+	return strings.TrimSuffix(str, "\n")
+}
+
+// files %files lines of the executables, man pages, artifacts and the license
+// files of the bundled modules
+func (s Specfile) files() string {
+	str := binFiles(s.Bins, s.Mans)
+	dirs := map[string]struct{}{}
+	for _, a := range s.Artifacts {
+		if a.License {
+			// the whole directory is listed below
+			if a.Dir != licenseDir || len(s.Licenses) == 0 {
+				str += "%license " + a.Dir + "/" + a.File + "\n"
+			}
+			continue
+		}
+		if _, ok := dirs[a.Dir]; !ok {
+			dirs[a.Dir] = struct{}{}
+			str += "%dir " + a.Dir + "\n"
+		}
+		str += a.Dir + "/" + a.File + "\n"
+	}
+	if len(s.Licenses) > 0 {
+		str += "%license " + licenseDir + "\n"
+	}
+	return strings.TrimSuffix(str, "\n")
+}
+
 func (s Specfile) Save() {
 	ioutil.WriteFile(filepath.Join(s.WorkingDirectory, s.Name+".spec"), s.Raw, 0644)
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_SpecfileLicenses(t *testing.T) {
+	s := Specfile{Licenses: []string{"LICENSE", "node_modules/ajv/LICENSE"}}
+	s.AddLicenseArtifact("LICENSES.bundled")
+	install := s.install(1)
+	if !strings.Contains(install, "install -D -m 0644 %{buildroot}%{nodejs_sitelib}/%{mod_name}/node_modules/ajv/LICENSE %{buildroot}%{_licensedir}/%{name}/node_modules/ajv/LICENSE") {
+		t.Errorf("Test Specfile.install() failed, license files not copied:\n%s", install)
+	}
+	// files.lst has the copies in %{nodejs_sitelib} already
+	if files := s.files(); strings.Contains(files, "%{nodejs_sitelib}") || strings.Count(files, "%license") != 1 || !strings.HasSuffix(files, "%license %{_licensedir}/%{name}") {
+		t.Errorf("Test Specfile.files() failed, got:\n%s", files)
+	}
+}
+
+func Test_SpecfileChecksums(t *testing.T) {
+	s := Specfile{Checksums: "sources.sha512"}
+	s.AddArtifact("har-validator.cdx.json", "%{_datadir}/%{name}")
+	if sources := s.sources(3); !strings.HasSuffix(sources, "Source4:\tsources.sha512\n") {
+		t.Errorf("Test Specfile.sources() failed, got:\n%s", sources)
+	}
+	if prep := s.prep("har-validator", 3); !strings.HasPrefix(prep, "(cd %{_sourcedir} && sha512sum --quiet -c %{SOURCE4})\n%nodejs_prep") {
+		t.Errorf("Test Specfile.prep() failed, got:\n%s", prep)
+	}
+}
+
+func Test_SpecfileObsScm(t *testing.T) {
+	s := Specfile{Backend: BackendObsScm, Checksums: "sources.sha512", Removals: []string{"rm -rf node_modules/fsevents/fsevents.node"}}
+	expected := "%setup -q -n types-node-%{version}\ncp %{SOURCE1} package-lock.json"
+	if prep := s.prep("@types/node", 2); prep != expected {
+		t.Errorf("Test Specfile.prep() failed, expected:\n%s\ngot:\n%s", expected, prep)
+	}
+	expected = "local-npm-registry %{_sourcedir} install --omit=dev\n# prebuilt binaries, openSUSE builds from source\nrm -rf node_modules/fsevents/fsevents.node"
+	if build := s.build(); build != expected {
+		t.Errorf("Test Specfile.build() failed, expected:\n%s\ngot:\n%s", expected, build)
+	}
+	if prep := (Specfile{}).prep("har-validator", 1); prep != "%nodejs_prep" {
+		t.Errorf("Test Specfile.prep() failed, got:\n%s", prep)
+	}
+}
@@ -1,56 +1,157 @@
 package main
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
-	"net/url"
+	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 )
 
-// Tarballs holds download uri of the module and its dependencies
-type Tarballs map[string]struct{}
+// Tarballs holds download uri of the module and its dependencies, with the
+// hashes the registry published for them
+type Tarballs map[string]Tarball
+
+// Tarball the hashes of a tarball, dist.integrity and dist.shasum. Sha256 is
+// computed from the tarball itself, the registry doesn't provide it.
+type Tarball struct {
+	Integrity string
+	Shasum    string
+	Sha256    string
+}
 
 // Append appends new tarball to Tarballs
-func (tb Tarballs) Append(uri string) {
+func (tb Tarballs) Append(uri, integrity, shasum string) {
 	if _, ok := tb[uri]; !ok {
-		tb[uri] = struct{}{}
+		tb[uri] = Tarball{Integrity: integrity, Shasum: shasum}
 	}
 }
 
-func (tb Tarballs) diff(m map[string]struct{}, wd string) {
-	s := "#!/bin/bash\n"
-	for k := range tb {
-		tgz := filepath.Base(k)
-		if _, ok := m[tgz]; !ok {
-			log.Printf("%s should be removed\n", tgz)
-			s += "osc delete " +tgz+"\n"
+// Checksums the hex encoded checksums of the tarball indexed by algorithm,
+// eg: "sha512", "sha1"
+func (t Tarball) Checksums() map[string]string {
+	m := map[string]string{}
+	for _, v := range strings.Fields(t.Integrity) {
+		a := strings.SplitN(v, "-", 2)
+		if len(a) < 2 {
+			continue
+		}
+		b, e := base64.StdEncoding.DecodeString(a[1])
+		if e != nil {
+			log.Printf("malformed integrity %s", v)
+			continue
+		}
+		m[a[0]] = hex.EncodeToString(b)
+	}
+	if _, ok := m["sha1"]; !ok && len(t.Shasum) > 0 {
+		m["sha1"] = t.Shasum
+	}
+	return m
+}
+
+// ChecksumFile checksums of the algorithm in "sha512sum -c" format, sorted by
+// file name, and the tarballs without such checksum
+func (tb Tarballs) ChecksumFile(alg string) (string, []string) {
+	lines := map[string]string{}
+	missing := []string{}
+	for _, k := range tb.Keys() {
+		f := filepath.Base(k)
+		if sum, ok := tb[k].Checksums()[alg]; ok {
+			lines[f] = sum + "  " + f + "\n"
+		} else {
+			missing = append(missing, f)
+		}
+	}
+	files := []string{}
+	for f := range lines {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	s := ""
+	for _, f := range files {
+		s += lines[f]
+	}
+	return s, missing
+}
+
+// SaveChecksums write sources.sha512 next to the spec, the tarballs without
+// sha512 (modules published before npm 5) are returned
+func (tb Tarballs) SaveChecksums(wd string) (string, []string) {
+	s, missing := tb.ChecksumFile("sha512")
+	f := filepath.Join(wd, "sources.sha512")
+	if e := ioutil.WriteFile(f, []byte(s), 0644); e != nil {
+		log.Fatalf("Can not write %s: %s", f, e)
+	}
+	return f, missing
+}
+
+// HashSha256 compute the sha256 of every tarball, the only verifier of the
+// verify_file service. The tarballs are checked against the registry hashes
+// first, so the sha256 is of the published tarball.
+func (tb Tarballs) HashSha256(cache ResponseCache) {
+	for _, uri := range tb.Keys() {
+		t := tb[uri]
+		body := getHttpBody(uri, cache)
+		if e := verifyChecksums(body, t.Checksums()); e != nil {
+			log.Fatalf("Can not verify %s: %s", uri, e)
 		}
+		sum := sha256.Sum256(body)
+		t.Sha256 = hex.EncodeToString(sum[:])
+		tb[uri] = t
 	}
-	ioutil.WriteFile(filepath.Join(wd, "remove.sh"), []byte(s), 0755)
 }
 
-// ToService convert tarball map to _service
-func (tb Tarballs) ToService(wd string) {
-	s := "<services>\n"
+// Stale file names of the old sources no longer in the tarballs
+func (tb Tarballs) Stale(old map[string]struct{}) []string {
+	current := map[string]struct{}{}
 	for k := range tb {
-		s += "\t<service name=\"download_url\" mode=\"localonly\">\n"
-		u, e := url.Parse(k)
-		if e != nil {
-			log.Fatalf("Can not parse download_url %s: %s", k, e)
+		current[filepath.Base(k)] = struct{}{}
+	}
+	stale := []string{}
+	for k := range old {
+		if _, ok := current[k]; !ok {
+			stale = append(stale, k)
 		}
-		s += "\t\t<param name=\"protocol\">" + u.Scheme + "</param>\n"
-		s += "\t\t<param name=\"host\">" + u.Host + "</param>\n"
-		s += "\t\t<param name=\"path\">" + u.Path + "</param>\n"
-		s += "\t</service>\n"
 	}
-	s += "</services>\n"
+	sort.Strings(stale)
+	return stale
+}
+
+// ToService update the npm download_url services of _service to the tarballs,
+// other services are kept. With verify every tarball gets a verify_file
+// service checking its hash. The file names of the replaced sources are returned.
+// With dryRun _service is left as it is, so the next run finds them again.
+func (tb Tarballs) ToService(wd string, verify, dryRun bool) []string {
+	sf := ReadServiceFile(wd)
+	stale := tb.Stale(sf.Tarballs())
+	if dryRun {
+		return stale
+	}
+	if e := sf.Update(tb, verify); e != nil {
+		log.Fatalf("Can not update _service: %s", e)
+	}
+	sf.Save(wd)
+	return stale
+}
 
-	m := parseService(wd)
-	if m != nil {
-		tb.diff(m, wd)
+// Keys the sorted tarball uris
+func (tb Tarballs) Keys() []string {
+	keys := []string{}
+	for k := range tb {
+		keys = append(keys, k)
 	}
-	ioutil.WriteFile(filepath.Join(wd, "_service"), []byte(s), 0644)
+	sort.Strings(keys)
+	return keys
 }
 
 // String convert tarball map to RPM Source string
@@ -63,3 +164,56 @@ func (tb Tarballs) String() string {
 	}
 	return s
 }
+
+// readTarballFile read a file from a gzipped npm tarball, name is relative to
+// the module root. npm packs modules in "package/", but some tarballs use
+// another top directory, so the first path component is ignored.
+func readTarballFile(body []byte, name string) ([]byte, error) {
+	gz, e := gzip.NewReader(bytes.NewReader(body))
+	if e != nil {
+		return nil, e
+	}
+	defer gz.Close()
+	name = path.Clean(strings.TrimPrefix(name, "./"))
+	tr := tar.NewReader(gz)
+	for {
+		hdr, e := tr.Next()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return nil, e
+		}
+		a := strings.SplitN(path.Clean(hdr.Name), "/", 2)
+		if len(a) == 2 && a[1] == name && hdr.Typeflag != tar.TypeDir {
+			return ioutil.ReadAll(tr)
+		}
+	}
+	return nil, fmt.Errorf("%s not found in tarball", name)
+}
+
+// listTarballFiles the regular files of a gzipped npm tarball, relative to the
+// module root, see readTarballFile
+func listTarballFiles(body []byte) ([]string, error) {
+	gz, e := gzip.NewReader(bytes.NewReader(body))
+	if e != nil {
+		return nil, e
+	}
+	defer gz.Close()
+	files := []string{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, e := tr.Next()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return nil, e
+		}
+		a := strings.SplitN(path.Clean(hdr.Name), "/", 2)
+		if len(a) == 2 && hdr.FileInfo().Mode().IsRegular() {
+			files = append(files, a[1])
+		}
+	}
+	return files, nil
+}
@@ -9,9 +9,9 @@ import (
 
 func Test_ToService(t *testing.T) {
 	tb := Tarballs{}
-	tb["https://registry.npmjs.org/punycode/-/punycode-2.1.1.tgz"] = struct{}{}
+	tb["https://registry.npmjs.org/punycode/-/punycode-2.1.1.tgz"] = Tarball{}
 	wd := "/tmp"
-	tb.ToService(wd)
+	tb.ToService(wd, false, false)
 	f := filepath.Join(wd, "_service")
 	dat, e := ioutil.ReadFile(f)
 	if e != nil {
@@ -24,5 +24,40 @@ func Test_ToService(t *testing.T) {
 	} else {
 		t.Errorf("Test Tarballs.ToService() failed: expected\n %s\n, got\n %s", answer, string(dat))
 	}
+
+	// a dry run reports the replaced sources but keeps _service
+	tb = Tarballs{"https://registry.npmjs.org/punycode/-/punycode-2.3.1.tgz": Tarball{}}
+	if stale := tb.ToService(wd, false, true); len(stale) != 1 || stale[0] != "punycode-2.1.1.tgz" {
+		t.Errorf("Test Tarballs.ToService() failed: expected punycode-2.1.1.tgz to be stale, got %v", stale)
+	}
+	if dat, _ := ioutil.ReadFile(f); string(dat) != answer {
+		t.Errorf("Test Tarballs.ToService() failed: a dry run changed _service to\n %s", string(dat))
+	}
 	os.Remove(f)
 }
+
+func Test_readTarballFile(t *testing.T) {
+	body := npmTarball(map[string]string{"LICENSE": "MIT"})
+	b, e := readTarballFile(body, "./LICENSE")
+	if e != nil || string(b) != "MIT" {
+		t.Errorf("Test readTarballFile() failed, got %s %v", b, e)
+	}
+	if _, e := readTarballFile(body, "COPYING"); e == nil {
+		t.Errorf("Test readTarballFile() failed, COPYING should not be found")
+	}
+}
+
+func Test_TarballsChecksumFile(t *testing.T) {
+	tb := Tarballs{}
+	// sha512 of "a", and a module published before npm 5 with its shasum only
+	tb.Append("https://registry.npmjs.org/b/-/b-1.0.0.tgz", "sha512-H0D8ktokFpR1CXnubPWC8tXX0o4YM13gWrxU0FYOD1MChgxlK/CNVgJSql50IQVG82n7u86MEs/HlXsmUv6adQ==", "")
+	tb.Append("https://registry.npmjs.org/a/-/a-0.1.0.tgz", "", "86f7e437faa5a7fce15d1ddcb9eaeaea377667b8")
+	s, missing := tb.ChecksumFile("sha512")
+	expected := "1f40fc92da241694750979ee6cf582f2d5d7d28e18335de05abc54d0560e0f5302860c652bf08d560252aa5e74210546f369fbbbce8c12cfc7957b2652fe9a75  b-1.0.0.tgz\n"
+	if s != expected || len(missing) != 1 || missing[0] != "a-0.1.0.tgz" {
+		t.Errorf("Test Tarballs.ChecksumFile() failed, expected %q and a-0.1.0.tgz missing, got %q %v", expected, s, missing)
+	}
+	if s, _ := tb.ChecksumFile("sha1"); s != "86f7e437faa5a7fce15d1ddcb9eaeaea377667b8  a-0.1.0.tgz\n" {
+		t.Errorf("Test Tarballs.ChecksumFile() failed, got %q", s)
+	}
+}
@@ -6,6 +6,12 @@ type TempData struct {
 	Licenses      Licenses
 	Tarballs      Tarballs
 	ResponseCache ResponseCache
+	Nodes         Nodes
+	Problems      *Problems
+	// Provides npm modules packaged in the distribution
+	Provides Provides
+	// Requirements excluded modules the package depends on
+	Requirements Requirements
 }
 
 // NewTempData initialize a new tempData structure
@@ -15,5 +21,21 @@ func NewTempData() TempData {
 		Licenses{},
 		Tarballs{},
 		ResponseCache{},
+		Nodes{},
+		&Problems{},
+		Provides{},
+		Requirements{},
 	}
 }
+
+// AppendNode records a resolved version of a Package, its license and tarball
+func (temp TempData) AppendNode(pkg Package, ver string) *Node {
+	_, ok := temp.Nodes[pkg.Name+":"+ver]
+	n := temp.Nodes.Append(pkg, ver)
+	if !ok && len(n.LicenseFile) > 0 {
+		n.resolveLicenseFile(temp.ResponseCache)
+	}
+	temp.Licenses.Append(n.License)
+	temp.Tarballs.Append(n.Tarball, n.Integrity, n.Shasum)
+	return n
+}
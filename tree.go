@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"log"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/bitly/go-simplejson"
@@ -78,17 +80,135 @@ func (t Tree) Inspect(idx int) string {
 	return s
 }
 
-// ToJson write dependency tree to file
-func (t Tree) ToJson() {
-	file := strings.Replace(reflect.ValueOf(t).MapKeys()[0].String(), ":", "-", -1)
-	b, e := json.MarshalIndent(t, "", "\t")
+// treeSchemaVersion the version of the tree json schema, see schemas/tree.schema.json.
+// bump it whenever fields of TreeDocument or TreeNode are added or changed
+const treeSchemaVersion = 3
+
+// TreeDocument the json representation of the dependency tree
+type TreeDocument struct {
+	SchemaVersion int      `json:"schemaVersion"`
+	Root          TreeNode `json:"root"`
+}
+
+// TreeNode a module in the json representation of the dependency tree
+type TreeNode struct {
+	Name           string     `json:"name"`
+	Version        string     `json:"version"`
+	Path           string     `json:"path"`
+	Resolved       string     `json:"resolved"`
+	Integrity      string     `json:"integrity"`
+	Shasum         string     `json:"shasum,omitempty"`
+	License        string     `json:"license"`
+	Type           string     `json:"type"`
+	Deduped        bool       `json:"deduped"`
+	InstallScripts []string   `json:"installScripts,omitempty"`
+	Dependencies   []TreeNode `json:"dependencies"`
+}
+
+// ToDocument convert the tree to its json representation, nodes holds the
+// metadata of the modules
+func (t Tree) ToDocument(nodes Nodes) TreeDocument {
+	doc := TreeDocument{SchemaVersion: treeSchemaVersion}
+	for k := range t {
+		// the root module is installed in place, its dependencies in its node_modules
+		doc.Root = t.toTreeNode(k, "", nodes)
+		doc.Root.Type = "root"
+	}
+	return doc
+}
+
+// toTreeNodes convert the tree's modules, path is the install path of their parent
+func (t Tree) toTreeNodes(path string, nodes Nodes) []TreeNode {
+	keys := make([]string, 0, len(t))
+	for k := range t {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	a := []TreeNode{}
+	for _, k := range keys {
+		name, _ := parseTreeKey(k)
+		a = append(a, t.toTreeNode(k, path+"node_modules/"+name, nodes))
+	}
+	return a
+}
+
+// toTreeNode convert the module k installed in path
+func (t Tree) toTreeNode(k, path string, nodes Nodes) TreeNode {
+	name, ver := parseTreeKey(k)
+	tn := TreeNode{Name: name, Version: ver, Path: path, Type: "prod", Dependencies: []TreeNode{}}
+	if n, ok := nodes[k]; ok {
+		tn.Resolved = n.Tarball
+		tn.Integrity = n.Integrity
+		tn.Shasum = n.Shasum
+		tn.License = n.License
+		tn.Deduped = n.Deduped
+		for _, v := range lifecycleScripts {
+			if _, ok := n.Scripts[v]; ok {
+				tn.InstallScripts = append(tn.InstallScripts, v)
+			}
+		}
+	}
+	if v := t[k]; v != nil {
+		if len(path) > 0 {
+			path += "/"
+		}
+		tn.Dependencies = v.toTreeNodes(path, nodes)
+	}
+	return tn
+}
+
+// parseTreeKey split a "name:version" key of the tree
+func parseTreeKey(k string) (string, string) {
+	i := strings.LastIndex(k, ":")
+	if i < 0 {
+		return k, ""
+	}
+	return k[:i], k[i+1:]
+}
+
+// ToJson write dependency tree to file, an empty file means "<name>-<version>.json"
+// in the osc working directory
+func (t Tree) ToJson(file, wd string, nodes Nodes) string {
+	if len(file) == 0 {
+		if len(t) == 0 {
+			log.Fatal("Can not write an empty dependency tree")
+		}
+		file = filepath.Join(wd, strings.Replace(reflect.ValueOf(t).MapKeys()[0].String(), ":", "-", -1)+".json")
+	}
+	b, e := json.MarshalIndent(t.ToDocument(nodes), "", "\t")
 	if e != nil {
 		log.Fatalf("Can not convert the dependency tree to json: %s", e)
 	}
-	if !strings.HasSuffix(file, ".json") {
-		file = file + ".json"
+	if e = ioutil.WriteFile(file, b, 0644); e != nil {
+		log.Fatalf("Can not write the dependency tree to %s: %s", file, e)
+	}
+	return file
+}
+
+// ReadTreeJson read a dependency tree json written by ToJson
+func ReadTreeJson(file string) TreeDocument {
+	b, e := ioutil.ReadFile(file)
+	if e != nil {
+		log.Fatalf("Can not read the dependency tree %s: %s", file, e)
+	}
+	doc := TreeDocument{}
+	if e = json.Unmarshal(b, &doc); e != nil {
+		log.Fatalf("Can not parse the dependency tree %s: %s", file, e)
+	}
+	// later versions only added optional fields, older trees are still readable
+	if doc.SchemaVersion < 1 || doc.SchemaVersion > treeSchemaVersion {
+		log.Fatalf("%s has schema version %d, only versions up to %d are supported.", file, doc.SchemaVersion, treeSchemaVersion)
+	}
+	return doc
+}
+
+// Walk call f on every node of the tree json, depth first
+func (tn TreeNode) Walk(f func(TreeNode)) {
+	f(tn)
+	for _, v := range tn.Dependencies {
+		v.Walk(f)
 	}
-	ioutil.WriteFile(file, b, 0644)
 }
 
 // BuildDependencyTree build a dependency tree
@@ -107,8 +227,7 @@ func BuildDependencyTree(uri string, ver *string, tree Tree, pt ParentTree, pare
 	}
 	// end
 
-	temp.Licenses.Append(pkg.License)
-	temp.Tarballs.Append(pkg.Json.Get(*ver).Get("dist").Get("tarball").MustString())
+	n := temp.AppendNode(pkg, *ver)
 
 	if len(parents) < 1 {
 		// root
@@ -118,11 +237,13 @@ func BuildDependencyTree(uri string, ver *string, tree Tree, pt ParentTree, pare
 		// if parents already has this dependency, don't append
 		if parents.Contains(pkg.Name + ":" + *ver) {
 			log.Printf("%s, version %s, has been provided via one of its parents, skiped.", pkg.Name, *ver)
+			n.Deduped = true
 			ahead = false
 		} else {
 			if ptParents, ok := pt[pkg.Name+":"+*ver]; ok {
 				log.Printf("%s, version %s, has been in the dependency tree but is not one of the new one's direct parents nor direct parents' counterparts, npm can not find it. try merging the old and the new to a place both can be found by their dependents.", pkg.Name, *ver)
 				log.Println("Computing an unified parent")
+				n.Deduped = true
 				parents = dedupeParents(ptParents, parents, tree)
 				if reflect.DeepEqual(parents.DirectParents(), ptParents.DirectParents()) {
 					log.Printf("Computed parent is exactly the same as the old parent, skipped")
@@ -148,7 +269,8 @@ func BuildDependencyTree(uri string, ver *string, tree Tree, pt ParentTree, pare
 
 	// calculate Child
 	if ahead {
-		dependencies := getDependencies(pkg.Json.Get(*ver).Get("dependencies"), temp.ResponseCache, temp.Exclusion)
+		dependencies := getDependencies(pkg.Json.Get(*ver).Get("dependencies"), pkg.Name+":"+*ver, temp)
+		n.Dependencies = dependencies
 		if len(dependencies) > 0 {
 			for i, k := range dependencies {
 				left := map[string]struct{}{}
@@ -182,7 +304,9 @@ func getSemver(versions semver.Collection, constriant string) semver.Semver {
 	return semver.Semver{}
 }
 
-func getDependencies(js *simplejson.Json, cache ResponseCache, exclusion Exclusion) []string {
+// getDependencies resolve the dependencies of the module k, unsatisfiable
+// constraints are recorded in temp.Problems and skipped
+func getDependencies(js *simplejson.Json, k string, temp TempData) []string {
 	upstreamDependencies, _ := js.Map()
 	// calculate next parent, we need to append current dependencies as parents
 	// for packages in the next loop here in this loop. because in the next loop,
@@ -203,17 +327,30 @@ func getDependencies(js *simplejson.Json, cache ResponseCache, exclusion Exclusi
 	// We need to skip [A, B], or our resolver will think B has already been in the tree.
 	dependencies := []string{}
 
-	for k, constriant := range upstreamDependencies {
-		childPkg := RegistryQuery(k, cache)
+	for name, constriant := range upstreamDependencies {
+		childPkg := RegistryQuery(name, temp.ResponseCache)
 		c, _ := constriant.(string)
 		version := getSemver(childPkg.Versions, c)
 		if len(version.String()) == 0 {
-			log.Fatalf("%s: no suitable version found for %s in %v.", k, constriant, childPkg.Versions)
+			log.Printf("%s: no suitable version found for %s in %v.", name, constriant, childPkg.Versions)
+			versions := []string{}
+			for _, v := range childPkg.Versions {
+				versions = append(versions, v.String())
+			}
+			temp.Problems.Unsatisfiable = append(temp.Problems.Unsatisfiable, Unsatisfiable{k, name, c, versions})
+			continue
 		}
-		if exclusion.Contains(k, version) {
-			log.Printf("%s version %s matched one of the packages known to be excluded, skipped.", k, version.String())
+		// prefer the version packaged in the distribution
+		if v, ok := temp.Provides.Satisfying(name, c); ok {
+			version = v
+		}
+		if r := temp.Exclusion.Exclude(name, version); r != nil {
+			if r.Requires {
+				temp.Requirements.Append(name, c)
+			}
+			log.Printf("%s version %s matched one of the packages known to be excluded, skipped.", name, version.String())
 		} else {
-			dependencies = append(dependencies, k+":"+version.String())
+			dependencies = append(dependencies, name+":"+version.String())
 		}
 	}
 
@@ -1,7 +1,6 @@
 package main
 
-//"reflect"
-//"testing"
+import "testing"
 
 /*func Test_dedupeParents(t *testing.T) {
 	var r Parents
@@ -24,3 +23,31 @@ package main
 		t.Errorf("dedupeParents() failed with result %v, should be %v", testResult, r)
 	}
 }*/
+
+func Test_ToDocument(t *testing.T) {
+	tree := Tree{}
+	child := Tree{}
+	grandchild := Tree{}
+	child["punycode:2.1.1"] = &grandchild
+	grandchild["ajv:6.12.6"] = &Tree{}
+	tree["har-validator:5.1.3"] = &child
+	nodes := Nodes{}
+	nodes["punycode:2.1.1"] = &Node{Name: "punycode", Version: "2.1.1", License: "MIT", Deduped: true}
+
+	doc := tree.ToDocument(nodes)
+	if doc.SchemaVersion != treeSchemaVersion || doc.Root.Type != "root" || len(doc.Root.Path) != 0 {
+		t.Errorf("Test Tree.ToDocument() failed, unexpected root %v", doc.Root)
+	}
+	if len(doc.Root.Dependencies) != 1 {
+		t.Fatalf("Test Tree.ToDocument() failed, expected 1 dependency, got %v", doc.Root.Dependencies)
+	}
+	n := doc.Root.Dependencies[0]
+	if n.Path == "node_modules/punycode" && n.License == "MIT" && n.Deduped {
+		t.Log("Test Tree.ToDocument() passed")
+	} else {
+		t.Errorf("Test Tree.ToDocument() failed, unexpected node %v", n)
+	}
+	if p := n.Dependencies[0].Path; p != "node_modules/punycode/node_modules/ajv" {
+		t.Errorf("Test Tree.ToDocument() failed, unexpected path %s", p)
+	}
+}
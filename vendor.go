@@ -0,0 +1,172 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// file names of the vendored node_modules
+const (
+	vendorArchive  = "node_modules.tar.zst"
+	vendorManifest = "node_modules.manifest"
+)
+
+// vendorMtime the mtime of every archive entry, the same npm uses for its
+// tarballs, so the archive only changes when its content does
+var vendorMtime = time.Date(1985, time.October, 26, 8, 15, 0, 0, time.UTC)
+
+// Compressor compress the data to the file, replaceable in tests
+type Compressor func(data []byte, file string) error
+
+// zstdCompressor compress with the zstd command, there's no zstd in the
+// standard library. One thread keeps the output reproducible.
+func zstdCompressor(data []byte, file string) error {
+	cmd := exec.Command("zstd", "-q", "-f", "-19", "-T1", "-o", file)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = os.Stderr
+	if e := cmd.Run(); e != nil {
+		return fmt.Errorf("zstd failed, is it installed? %s", e)
+	}
+	return nil
+}
+
+// vendorEntry a file or directory of the vendored node_modules
+type vendorEntry struct {
+	Name string
+	Mode int64
+	Body []byte
+	Dir  bool
+}
+
+// VendorModules lay the tarballs of the bundled modules out in the hoisted
+// node_modules structure of the tree, and write them into one reproducible
+// archive plus a manifest in the working directory. Entries are sorted, owned
+// by root with a fixed mtime. Returns the archive and manifest file names.
+func VendorModules(doc TreeDocument, nodes Nodes, cache ResponseCache, wd string, compress Compressor) (string, string) {
+	entries := map[string]vendorEntry{}
+	manifest := ""
+	modules := []TreeNode{}
+	doc.Root.Walk(func(tn TreeNode) {
+		if len(tn.Path) > 0 {
+			modules = append(modules, tn)
+		}
+	})
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Path < modules[j].Path })
+
+	for _, tn := range modules {
+		n, ok := nodes[tn.Name+":"+tn.Version]
+		if !ok || len(n.Tarball) == 0 {
+			log.Fatalf("%s %s has no tarball to vendor", tn.Name, tn.Version)
+		}
+		body := getHttpBody(n.Tarball, cache)
+		if e := verifyChecksums(body, n.Checksums()); e != nil {
+			log.Fatalf("%s: %s", filepath.Base(n.Tarball), e)
+		}
+		if e := addVendorModule(entries, tn.Path, body); e != nil {
+			log.Fatalf("Can not unpack %s: %s", filepath.Base(n.Tarball), e)
+		}
+		manifest += tn.Path + "\t" + tn.Name + "\t" + tn.Version + "\t" + n.Integrity + "\n"
+	}
+
+	data, e := vendorTar(entries)
+	if e != nil {
+		log.Fatalf("Can not create %s: %s", vendorArchive, e)
+	}
+	if e := compress(data, filepath.Join(wd, vendorArchive)); e != nil {
+		log.Fatalf("Can not create %s: %s", vendorArchive, e)
+	}
+	if e := ioutil.WriteFile(filepath.Join(wd, vendorManifest), []byte(manifest), 0644); e != nil {
+		log.Fatalf("Can not write %s: %s", vendorManifest, e)
+	}
+	return vendorArchive, vendorManifest
+}
+
+// addVendorModule unpack the regular files of a gzipped npm tarball into dir,
+// the first path component ("package/") is replaced like npm does
+func addVendorModule(entries map[string]vendorEntry, dir string, body []byte) error {
+	gz, e := gzip.NewReader(bytes.NewReader(body))
+	if e != nil {
+		return e
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, e := tr.Next()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return e
+		}
+		a := strings.SplitN(path.Clean(hdr.Name), "/", 2)
+		if len(a) < 2 || !hdr.FileInfo().Mode().IsRegular() || strings.HasPrefix(a[1], "../") {
+			continue
+		}
+		b, e := ioutil.ReadAll(tr)
+		if e != nil {
+			return e
+		}
+		// npm normalizes permissions too, only the executable bit matters
+		mode := int64(0644)
+		if hdr.Mode&0111 != 0 {
+			mode = 0755
+		}
+		name := path.Join(dir, a[1])
+		entries[name] = vendorEntry{name, mode, b, false}
+		for d := path.Dir(name); d != "." && d != "/"; d = path.Dir(d) {
+			entries[d] = vendorEntry{d, 0755, nil, true}
+		}
+	}
+	return nil
+}
+
+// vendorTar a tar of the entries sorted by name, with fixed owner and mtime
+func vendorTar(entries map[string]vendorEntry) ([]byte, error) {
+	names := []string{}
+	for k := range entries {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, k := range names {
+		v := entries[k]
+		hdr := &tar.Header{
+			Name:    v.Name,
+			Mode:    v.Mode,
+			ModTime: vendorMtime,
+			Uname:   "root",
+			Gname:   "root",
+		}
+		if v.Dir {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+		} else {
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = int64(len(v.Body))
+		}
+		if e := tw.WriteHeader(hdr); e != nil {
+			return nil, e
+		}
+		if _, e := tw.Write(v.Body); e != nil {
+			return nil, e
+		}
+	}
+	if e := tw.Close(); e != nil {
+		return nil, e
+	}
+	return buf.Bytes(), nil
+}
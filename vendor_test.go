@@ -0,0 +1,84 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_VendorModules(t *testing.T) {
+	wd, e := ioutil.TempDir("", "node2rpm")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(wd)
+
+	cache := ResponseCache{}
+	nodes := Nodes{}
+	for k, files := range map[string]map[string]string{
+		"a:1.0.0": {"package.json": "{}", "index.js": "a"},
+		"b:2.0.0": {"package.json": "{}", "lib/b.js": "b"},
+	} {
+		name, ver := parseTreeKey(k)
+		body := npmTarball(files)
+		sum := sha1.Sum(body)
+		uri := "https://registry.npmjs.org/" + name + "/-/" + name + "-" + ver + ".tgz"
+		cache[uri] = body
+		nodes[k] = &Node{Name: name, Version: ver, Tarball: uri, Shasum: hex.EncodeToString(sum[:])}
+	}
+	doc := TreeDocument{Root: TreeNode{Name: "root", Version: "1.0.0", Dependencies: []TreeNode{
+		{Name: "a", Version: "1.0.0", Path: "node_modules/a", Dependencies: []TreeNode{
+			{Name: "b", Version: "2.0.0", Path: "node_modules/a/node_modules/b"},
+		}},
+	}}}
+
+	raw := func(data []byte, file string) error {
+		return ioutil.WriteFile(file, data, 0644)
+	}
+	archive, manifest := VendorModules(doc, nodes, cache, wd, raw)
+	first, _ := ioutil.ReadFile(filepath.Join(wd, archive))
+	VendorModules(doc, nodes, cache, wd, raw)
+	second, _ := ioutil.ReadFile(filepath.Join(wd, archive))
+	if !bytes.Equal(first, second) {
+		t.Errorf("Test VendorModules() failed, the archive is not reproducible")
+	}
+
+	names := []string{}
+	tr := tar.NewReader(bytes.NewReader(first))
+	for {
+		hdr, e := tr.Next()
+		if e == io.EOF {
+			break
+		}
+		if !hdr.ModTime.Equal(vendorMtime) || hdr.Uid != 0 || hdr.Uname != "root" {
+			t.Errorf("Test VendorModules() failed, %s has mtime %s owner %s", hdr.Name, hdr.ModTime, hdr.Uname)
+		}
+		names = append(names, hdr.Name)
+	}
+	expected := []string{
+		"node_modules/",
+		"node_modules/a/",
+		"node_modules/a/index.js",
+		"node_modules/a/node_modules/",
+		"node_modules/a/node_modules/b/",
+		"node_modules/a/node_modules/b/lib/",
+		"node_modules/a/node_modules/b/lib/b.js",
+		"node_modules/a/node_modules/b/package.json",
+		"node_modules/a/package.json",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Test VendorModules() failed, expected %v, got %v", expected, names)
+	}
+
+	m, _ := ioutil.ReadFile(filepath.Join(wd, manifest))
+	if string(m) != "node_modules/a\ta\t1.0.0\t\nnode_modules/a/node_modules/b\tb\t2.0.0\t\n" {
+		t.Errorf("Test VendorModules() failed, wrong manifest %q", m)
+	}
+}